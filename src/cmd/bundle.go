@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"slices"
+	"strings"
+)
+
+type bundleCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	bundleOpts bundleOpts
+}
+
+func newBundleCmd(globalOpts *globalOpts) *bundleCmd {
+	cmd := &bundleCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "bundle",
+		Short: "Create and import bundles of versions for hosts without internet access.",
+		Long: `Create and import bundles of versions for hosts without internet access.
+
+A bundle is a tar file with the archives of one or more versions, their SHASUMS256.txt and signatures, and their
+entries of index.json. Importing it stores the archives in the cache, and the other files in <NVMC_HOME>/offline.
+With --offline, install, ls-remote and version ranges then use the imported versions, and fail instead of downloading
+anything else. The signature of SHASUMS256.txt is verified again when a version is installed.`,
+		Example: `# On a host with internet access, bundle the newest 18 and 20 for linux x64.
+$ nvmc bundle create 18 20 --platform linux --arch x64 -o node.tar
+
+# On the host without internet access, import the bundle and install from it.
+$ nvmc bundle import node.tar
+$ nvmc install 20 --offline`,
+		Args: cobra.ExactArgs(0),
+	}
+
+	cmd.globalOpts = globalOpts
+	createCommand := &cobra.Command{
+		Use:   "create <version>...",
+		Short: "Download <version>... into a bundle.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return bundleCreate(args, *cmd.globalOpts, cmd.bundleOpts)
+		},
+	}
+	createCommand.Flags().StringVarP(&cmd.bundleOpts.out, "out", "o", defaultBundleOpts.out, "File the bundle is written to.")
+	addTargetFlags(createCommand.Flags(), &cmd.bundleOpts.target)
+	cmd.command.AddCommand(createCommand)
+	cmd.command.AddCommand(&cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Import the versions of <bundle> for --offline.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return bundleImport(args[0], *cmd.globalOpts)
+		},
+	})
+
+	return cmd
+}
+
+func bundleCreate(expressions []string, globalOpts globalOpts, bundleOpts bundleOpts) error {
+	reporter := globalOpts.reporter()
+	target, err := bundleOpts.target.target()
+	if err != nil {
+		return err
+	}
+	globalOpts = globalOpts.forTarget(target)
+
+	versions := make([]string, 0, len(expressions))
+	for _, expression := range expressions {
+		version, err := resolveRemoteVersion(expression, target, globalOpts)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+
+	// The index entries let install resolve ranges and lts offline.
+	entries, err := util.FetchIndex(globalOpts.downloadUrl, globalOpts.downloadOptions())
+	if err != nil {
+		return errors.New("unable to bundle index.json of " + globalOpts.downloadUrl + ", " + err.Error())
+	}
+	entries = slices.DeleteFunc(entries, func(entry util.IndexEntry) bool {
+		return !slices.Contains(versions, entry.Version)
+	})
+	slices.Reverse(entries)
+	index, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	indexName, err := util.OfflineFileName(globalOpts.downloadUrl + "/index.json")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(bundleOpts.out)
+	if err != nil {
+		return err
+	}
+	writer := util.NewBundleWriter(file)
+	err = writer.Add(indexName, index)
+	for _, version := range versions {
+		if err == nil {
+			err = bundleVersion(writer, version, target, globalOpts, reporter)
+		}
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(bundleOpts.out)
+		return err
+	}
+
+	reporter.Message("created " + bundleOpts.out + " with " + strings.Join(versions, ", ") + " for " + target.String())
+	return nil
+}
+
+// bundleVersion downloads the archive of version for target into the cache, and writes it to the bundle with the
+// sums file and signatures that it is verified with.
+func bundleVersion(writer *util.BundleWriter, version string, target util.Target, globalOpts globalOpts, reporter util.Reporter) error {
+	installationInfo, err := util.GetInstallationInfo(version, target)
+	if err != nil {
+		return err
+	}
+	formats, err := archiveFormats(version, target, globalOpts)
+	if err != nil {
+		return err
+	}
+	verifier, err := fetchChecksumVerifier(version, installationInfo, formats, globalOpts, reporter)
+	if errors.Is(err, util.ErrChecksumNotFound) {
+		return errors.New("unable to bundle " + version + " without verifying it, " + err.Error())
+	} else if err != nil {
+		return err
+	}
+
+	versionUrl := globalOpts.downloadUrl + "/" + version
+	downloadOptions := globalOpts.downloadOptions()
+	downloadOptions.Reporter = reporter
+	archivePath, err := util.CacheDownload(versionUrl+"/"+verifier.Name, verifier, downloadOptions)
+	if err != nil {
+		return err
+	}
+
+	// The sums file is bundled as published, with its signatures, so that install verifies it again.
+	sumsFileName := verifier.Algorithm.SumsFileName()
+	for _, fileName := range []string{sumsFileName, sumsFileName + ".sig", sumsFileName + ".asc"} {
+		contents := new(bytes.Buffer)
+		err := util.Download(versionUrl+"/"+fileName, contents, globalOpts.downloadOptions())
+		if errors.Is(err, util.ErrNotFound) && fileName != sumsFileName {
+			continue
+		} else if err != nil {
+			return err
+		}
+		name, err := util.OfflineFileName(versionUrl + "/" + fileName)
+		if err != nil {
+			return err
+		}
+		if err := writer.Add(name, contents.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	name, err := util.OfflineFileName(versionUrl + "/" + verifier.Name)
+	if err != nil {
+		return err
+	}
+	return writer.AddFile(name, archivePath)
+}
+
+func bundleImport(path string, globalOpts globalOpts) error {
+	reporter := globalOpts.reporter()
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archives, err := util.ImportBundle(file)
+	for _, archive := range archives {
+		reporter.Message("imported " + archive)
+	}
+	if err != nil {
+		return errors.New("unable to import " + path + ", " + err.Error())
+	}
+	if len(archives) == 0 {
+		reporter.Message(path + " has no archives to import")
+	}
+	return nil
+}
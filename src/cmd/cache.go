@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cacheCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	cacheOpts  cacheOpts
+}
+
+func newCacheCmd(globalOpts *globalOpts) *cacheCmd {
+	cmd := &cacheCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cache of downloaded archives.",
+		Long: `Manage the cache of downloaded archives.
+
+Archives are cached in <NVMC_HOME>/cache by file name and checksum, so reinstalling a version does not download it
+again. Interrupted downloads are kept in the cache and resumed by the next install of the same version.`,
+		Example: `# Remove archives that have not been used for 30 days.
+$ nvmc cache prune --older-than 30d`,
+		Args: cobra.ExactArgs(0),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the cached archives.",
+		Args:    cobra.ExactArgs(0),
+		RunE: func(command *cobra.Command, args []string) error {
+			return cacheList()
+		},
+	})
+	cmd.command.AddCommand(&cobra.Command{
+		Use:   "clean",
+		Short: "Remove every cached archive.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(command *cobra.Command, args []string) error {
+			return util.CleanCache()
+		},
+	})
+	pruneCommand := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached archives that have not been used recently.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(command *cobra.Command, args []string) error {
+			return cachePrune(cmd.cacheOpts)
+		},
+	}
+	pruneCommand.Flags().StringVar(&cmd.cacheOpts.olderThan, "older-than", defaultCacheOpts.olderThan, "Remove archives last used longer ago than this, e.g. 30d or 12h.")
+	cmd.command.AddCommand(pruneCommand)
+
+	return cmd
+}
+
+func cacheList() error {
+	entries, err := util.ListCache()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("the cache is empty")
+		return nil
+	}
+
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s %.1f MB, last used %s", entry.Name, float64(entry.Size)/1024/1024, entry.LastUsed.Format(time.DateTime))
+		if entry.Partial {
+			line = line + " (partial)"
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+func cachePrune(cacheOpts cacheOpts) error {
+	olderThan, err := parseAge(cacheOpts.olderThan)
+	if err != nil {
+		return err
+	}
+
+	removed, err := util.PruneCache(olderThan)
+	for _, entry := range removed {
+		fmt.Println("removed " + entry.Name)
+	}
+
+	return err
+}
+
+// parseAge parses a duration that can also be given in days, e.g. 30d.
+func parseAge(age string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(age, "d"); found {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, errors.New("invalid age " + age + ", expected a duration such as 30d or 12h")
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(age)
+	if err != nil || duration < 0 {
+		return 0, errors.New("invalid age " + age + ", expected a duration such as 30d or 12h")
+	}
+	return duration, nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"nvmc/util"
+	"os"
+	"strings"
+)
+
+type configCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+}
+
+func newConfigCmd(globalOpts *globalOpts) *configCmd {
+	cmd := &configCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the persistent configuration.",
+		Long: `Manage the persistent configuration.
+
+Every global flag can be configured. A flag given on the command line takes precedence over its NVMC_*
+environment variable (e.g. NVMC_DOWNLOAD_URL for --download-url), which takes precedence over the config file
+stored in <NVMC_HOME>/config.json, which takes precedence over the default.`,
+		Example: `# Use an internal mirror for every command.
+$ nvmc config set download-url https://mirror.example.com/node
+
+# Show every setting and where its value comes from.
+$ nvmc config list`,
+		Args: cobra.ExactArgs(0),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.AddCommand(&cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the value of <key>.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return configGet(command.Root().PersistentFlags(), args[0])
+		},
+	})
+	cmd.command.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set <key> to <value> in the config file.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(command *cobra.Command, args []string) error {
+			return configSet(command.Root().PersistentFlags(), cmd.globalOpts, args[0], args[1])
+		},
+	})
+	cmd.command.AddCommand(&cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove <key> from the config file.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return configUnset(args[0])
+		},
+	})
+	cmd.command.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Print every key with its value and where the value comes from.",
+		Args:    cobra.ExactArgs(0),
+		RunE: func(command *cobra.Command, args []string) error {
+			return configList(command.Root().PersistentFlags())
+		},
+	})
+
+	return cmd
+}
+
+// applyConfig sets the global flags that were not given on the command line from their environment variable
+// or the config file.
+func applyConfig(flags *pflag.FlagSet) error {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	var applyErr error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed {
+			return
+		}
+		value, source := configValue(flag, config)
+		if err := flag.Value.Set(value); err != nil {
+			applyErr = errors.New("invalid value " + value + " for " + flag.Name + " from " + source + ": " + err.Error())
+		}
+	})
+
+	return applyErr
+}
+
+// configValue returns the effective value of flag and where the value comes from.
+func configValue(flag *pflag.Flag, config util.Config) (string, string) {
+	if flag.Changed {
+		return flag.Value.String(), "flag --" + flag.Name
+	}
+	if value, found := os.LookupEnv(util.ConfigEnv(flag.Name)); found {
+		return value, "environment variable " + util.ConfigEnv(flag.Name)
+	}
+	if value, found := config[flag.Name]; found {
+		return value, "config file"
+	}
+	return flag.DefValue, "default"
+}
+
+func configFlag(flags *pflag.FlagSet, key string) (*pflag.Flag, error) {
+	flag := flags.Lookup(key)
+	if flag == nil {
+		return nil, errors.New("unknown config key " + key + ", run nvmc config list for the available keys")
+	}
+	return flag, nil
+}
+
+func configGet(flags *pflag.FlagSet, key string) error {
+	flag, err := configFlag(flags, key)
+	if err != nil {
+		return err
+	}
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	value, _ := configValue(flag, config)
+	fmt.Println(value)
+
+	return nil
+}
+
+// configSet saves value for key in the config file. The flag of key is bound to globalOpts, which are validated with
+// value before it is saved, so that an invalid value does not make every other command fail.
+func configSet(flags *pflag.FlagSet, globalOpts *globalOpts, key string, value string) error {
+	flag, err := configFlag(flags, key)
+	if err != nil {
+		return err
+	}
+	if err := flag.Value.Set(value); err != nil {
+		return errors.New("invalid value " + value + " for " + key + ": " + err.Error())
+	}
+	if err := globalOpts.validate(); err != nil {
+		return err
+	}
+
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+	config[key] = value
+
+	return config.Save()
+}
+
+func configUnset(key string) error {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if _, found := config[key]; !found {
+		return errors.New("config key " + key + " is not set in the config file")
+	}
+	delete(config, key)
+
+	return config.Save()
+}
+
+func configList(flags *pflag.FlagSet) error {
+	config, err := util.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		value, source := configValue(flag, config)
+		if len(value) > 0 && (strings.HasSuffix(flag.Name, "-token") || strings.HasSuffix(flag.Name, "-password")) {
+			value = "********"
+		}
+		fmt.Println(flag.Name + " = " + value + " (" + source + ")")
+	})
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+)
+
+type currentCmd struct {
+	command     *cobra.Command
+	globalOpts  *globalOpts
+	currentOpts currentOpts
+}
+
+func newCurrentCmd(globalOpts *globalOpts) *currentCmd {
+	cmd := &currentCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "current",
+		Short: "Print the current node version.",
+		Example: `$ nvmc current
+
+# Explain where the current version comes from.
+$ nvmc current --source`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.currentOpts.source, "source", defaultCurrentOpts.source, "Print the file that supplied the version.")
+
+	return cmd
+}
+
+func (c *currentCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return current(*c.globalOpts, c.currentOpts)
+	}
+}
+
+func current(globalOpts globalOpts, currentOpts currentOpts) error {
+	version, err := activeVersion()
+	if err != nil {
+		return err
+	}
+
+	if !currentOpts.source {
+		fmt.Println(version)
+		return nil
+	}
+
+	if len(os.Getenv(util.ShellVersionEnv)) > 0 {
+		if source := os.Getenv(util.ShellSourceEnv); len(source) > 0 {
+			fmt.Println(version + " (from this shell, activated by nvmc shell from " + source + ")")
+		} else {
+			fmt.Println(version + " (from this shell, activated by nvmc shell)")
+		}
+		return nil
+	}
+
+	nodeSymLink, err := util.GetSymLinkPath()
+	if err != nil {
+		return err
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	versionFile, err := util.FindVersionFile(workingDir)
+	if errors.Is(err, util.ErrVersionFileNotFound) {
+		fmt.Println(version + " (from the global symlink " + nodeSymLink + ")")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if requested, err := resolveInstalledVersion(versionFile.Expression, util.NativeTarget(), globalOpts); err == nil && requested == version {
+		fmt.Println(version + " (from " + describeVersionFile(versionFile) + " with version " + versionFile.Expression + ")")
+	} else {
+		fmt.Println(version + " (from the global symlink " + nodeSymLink + ", " + describeVersionFile(versionFile) + " requests " + versionFile.Expression + ", run nvmc use to switch)")
+	}
+
+	return nil
+}
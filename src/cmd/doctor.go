@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+)
+
+type doctorCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	doctorOpts doctorOpts
+}
+
+func newDoctorCmd(globalOpts *globalOpts) *doctorCmd {
+	cmd := &doctorCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the nvmc installation for problems.",
+		Long: `Check the nvmc installation for problems, and print how to fix them.
+
+Checks that NVMC_HOME is writable, that the symlink of the active version points to an installed version, that it is
+the first directory in PATH with node, npm or npx, that no installed version is missing node, and that no files were
+left behind by an install that did not finish.
+
+Exits with code 1 when an error is found, or with --strict when a warning is found, so that it can be used in CI.
+With --output json, each check is printed as a JSON line.`,
+		Example: `$ nvmc doctor
+
+# Also fail on warnings.
+$ nvmc doctor --strict`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.doctorOpts.strict, "strict", defaultDoctorOpts.strict, "Exit with code 1 on warnings too.")
+
+	return cmd
+}
+
+func (c *doctorCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := doctor(*c.globalOpts, c.doctorOpts)
+		silenceExitCodeError(cmd, err)
+		return err
+	}
+}
+
+func doctor(globalOpts globalOpts, doctorOpts doctorOpts) error {
+	diagnostics := util.Diagnose(os.Getenv("PATH"), os.Getenv(util.ShellPathEnv))
+
+	failed := false
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Level == util.DiagnosticError || (doctorOpts.strict && diagnostic.Level == util.DiagnosticWarning) {
+			failed = true
+		}
+
+		if globalOpts.output == "json" {
+			contents, err := json.Marshal(diagnostic)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(contents))
+			continue
+		}
+		fmt.Println("[" + string(diagnostic.Level) + "] " + diagnostic.Message)
+		if len(diagnostic.Fix) > 0 {
+			fmt.Println("    fix: " + diagnostic.Fix)
+		}
+	}
+
+	if failed {
+		return &exitCodeError{1}
+	}
+	return nil
+}
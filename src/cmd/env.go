@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+)
+
+type envCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	envOpts    envOpts
+}
+
+func newEnvCmd(globalOpts *globalOpts) *envCmd {
+	cmd := &envCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "env",
+		Short: "Print shell code that enables nvmc shell for the current shell.",
+		Long: `Print shell code that enables nvmc shell for the current shell.
+
+The code defines an nvmc wrapper function so that nvmc shell <version> switches the node version of the current
+shell only. With --use-on-cd, the shell also switches versions whenever the working directory changes to a
+directory with a .nvmrc, .node-version, or package.json, and falls back to the global symlink when leaving it.`,
+		Example: `# ~/.bashrc
+eval "$(nvmc env --shell bash --use-on-cd)"
+
+# ~/.zshrc
+eval "$(nvmc env --shell zsh --use-on-cd)"
+
+# ~/.config/fish/config.fish
+nvmc env --shell fish --use-on-cd | source
+
+# PowerShell $PROFILE
+nvmc env --shell powershell --use-on-cd | Out-String | Invoke-Expression`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().StringVar(&cmd.envOpts.shell, "shell", defaultEnvOpts.shell, "Shell to print code for, one of bash, zsh, fish, powershell. Detected when not set.")
+	cmd.command.Flags().BoolVar(&cmd.envOpts.useOnCd, "use-on-cd", defaultEnvOpts.useOnCd, "Switch versions automatically when changing directories.")
+
+	return cmd
+}
+
+func (c *envCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return env(c.envOpts)
+	}
+}
+
+func env(envOpts envOpts) error {
+	targetShell, err := shellFromOpts(envOpts.shell)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(util.ShellHook(targetShell, envOpts.useOnCd))
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+type execCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	execOpts   execOpts
+}
+
+func newExecCmd(globalOpts *globalOpts) *execCmd {
+	cmd := &execCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "exec [version] -- <command> [args...]",
+		Short: "Run <command> with <version> first in PATH, without changing the current version.",
+		Long: `Run <command> with <version> first in PATH, without changing the current version.
+
+The exit code of <command> is returned, and interrupt and terminate signals are forwarded to it.
+When <version> is omitted, it is read from the nearest .nvmrc, .node-version, or package.json.`,
+		Example: `# Run the tests with version 18.2.0.
+$ nvmc exec 18.2.0 -- npm test
+
+# Run the tests with the newest 20.x version, installing it when it is missing.
+$ nvmc exec 20 --install -- npm test
+
+# Run the tests with the x64 build of 18.2.0, e.g. to reproduce a problem with a native module.
+$ nvmc exec 18.2.0 --arch x64 --install -- npm test`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.execOpts.install, "install", defaultExecOpts.install, "Install <version> when it is not installed.")
+	addTargetFlags(cmd.command.Flags(), &cmd.execOpts.target)
+
+	return cmd
+}
+
+func (c *execCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var versionArgs, commandArgs []string
+		switch dash := cmd.ArgsLenAtDash(); {
+		case dash == -1:
+			versionArgs, commandArgs = args[:1], args[1:]
+		case dash <= 1:
+			versionArgs, commandArgs = args[:dash], args[dash:]
+		default:
+			return errors.New("expected at most one version before --, received " + strconv.Itoa(dash))
+		}
+		if len(commandArgs) == 0 {
+			return errors.New("a command to run is required")
+		}
+
+		err := execVersion(versionArgs, commandArgs, *c.globalOpts, c.execOpts)
+		silenceExitCodeError(cmd, err)
+		return err
+	}
+}
+
+// execVersion runs commandArgs with the version from versionArgs first in PATH.
+func execVersion(versionArgs []string, commandArgs []string, globalOpts globalOpts, execOpts execOpts) error {
+	expression, err := versionExpression(versionArgs)
+	if err != nil {
+		return err
+	}
+
+	target, err := execOpts.target.target()
+	if err != nil {
+		return err
+	}
+	version, err := resolveInstalledVersion(expression, target, globalOpts)
+	var activation *util.ShellActivation
+	if err == nil {
+		activation, err = shellActivation(version, "")
+	}
+	if err != nil && execOpts.install {
+		version, err = resolveRemoteVersion(expression, target, globalOpts)
+		if err != nil {
+			return err
+		}
+		installOpts := defaultInstallOpts
+		installOpts.target = execOpts.target
+		installOpts.skipAutoUse = true
+		if err := install(version, globalOpts, installOpts); err != nil {
+			return err
+		}
+		activation, err = shellActivation(util.InstallName(version, target), "")
+	}
+	if err != nil {
+		return err
+	}
+
+	// The command is looked up using PATH, so it must point at the version before the command is created.
+	if err := os.Setenv("PATH", activation.Path); err != nil {
+		return err
+	}
+	if err := os.Setenv(util.ShellPathEnv, activation.BinPath); err != nil {
+		return err
+	}
+	if err := os.Setenv(util.ShellVersionEnv, activation.Version); err != nil {
+		return err
+	}
+
+	child := exec.Command(commandArgs[0], commandArgs[1:]...)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	// The child shares the terminal's process group, so it receives Ctrl-C from the terminal itself.
+	// The signals are caught and discarded instead of ignored, so the child does not inherit them as ignored,
+	// and nvmc keeps waiting for the child to exit.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		for range signals {
+		}
+	}()
+
+	if err := child.Start(); err != nil {
+		return err
+	}
+
+	err = child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return &exitCodeError{128 + int(status.Signal())}
+		}
+		return &exitCodeError{exitErr.ExitCode()}
+	}
+
+	return err
+}
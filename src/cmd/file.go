@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"nvmc/util"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileVersion returns the version of the archive of --from-file, and sets the target of installOpts to the target of
+// the archive. The version argument and target options are optional, but must match the archive when given.
+func fileVersion(args []string, installOpts *installOpts) (string, error) {
+	if installOpts.fromSource {
+		return "", errors.New("--from-file can not be used with --from-source")
+	}
+	version, target, _, err := util.ParseArchiveName(filepath.Base(installOpts.fromFile))
+	if err != nil {
+		return "", err
+	}
+
+	if len(args) > 0 {
+		expected, err := util.NormalizeVersion(args[0])
+		if err != nil {
+			return "", err
+		}
+		if expected != version {
+			return "", errors.New("the archive " + installOpts.fromFile + " is " + version + ", not " + expected)
+		}
+	}
+	opts := installOpts.target
+	if len(opts.platform) > 0 || len(opts.arch) > 0 || len(opts.libc) > 0 {
+		expected, err := opts.target()
+		if err != nil {
+			return "", err
+		}
+		if expected != target {
+			return "", errors.New("the archive " + installOpts.fromFile + " is for " + target.String() + ", not " + expected.String())
+		}
+	}
+
+	libc := target.Libc
+	if len(libc) == 0 {
+		libc = "glibc"
+	}
+	installOpts.target = targetOpts{target.Platform, target.Arch, libc}
+	return version, nil
+}
+
+// fileChecksumVerifier returns the verifier of the archive of --from-file for --checksum or --sums-file, or nil when
+// neither was given.
+func fileChecksumVerifier(installOpts installOpts, globalOpts globalOpts) (*util.ChecksumVerifier, error) {
+	name := filepath.Base(installOpts.fromFile)
+	algorithm, err := util.ParseChecksumAlgorithm(globalOpts.checksumAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(installOpts.checksum) > 0 && len(installOpts.sumsFile) > 0:
+		return nil, errors.New("--checksum and --sums-file can not be used together")
+	case len(installOpts.checksum) > 0:
+		// The checksum is either <algorithm>:<hex>, or only the hex checksum with --checksum-algorithm.
+		checksum := installOpts.checksum
+		if prefix, hex, found := strings.Cut(checksum, ":"); found {
+			if algorithm, err = util.ParseChecksumAlgorithm(prefix); err != nil {
+				return nil, err
+			}
+			checksum = hex
+		}
+		sums, err := util.ParseSums([]byte(checksum+"  "+name), algorithm)
+		if err != nil {
+			return nil, errors.New("invalid checksum " + installOpts.checksum + ", expected a " + string(algorithm) + " hex checksum")
+		}
+		return util.NewChecksumVerifier(sums, name, algorithm)
+	case len(installOpts.sumsFile) > 0:
+		contents, err := os.ReadFile(installOpts.sumsFile)
+		if err != nil {
+			return nil, err
+		}
+		sums, err := util.ParseSums(contents, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		return util.NewChecksumVerifier(sums, name, algorithm)
+	}
+	return nil, nil
+}
+
+// stageFileInstall verifies the archive of --from-file and extracts it into tempDir, with the install.json that
+// records where it came from.
+func stageFileInstall(version string, installationInfo *util.InstallationInfo, tempDir string, installOpts installOpts, aliases []string, globalOpts globalOpts, reporter util.Reporter) (*util.InstallMetadata, error) {
+	path, err := filepath.Abs(installOpts.fromFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var verifier *util.ChecksumVerifier
+	if !installOpts.skipChecksumValidation {
+		verifier, err = fileChecksumVerifier(installOpts, globalOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if verifier != nil {
+		if err := verifier.VerifyFile(path); err != nil {
+			return nil, err
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "warning: installing "+path+" without verifying it, use --checksum or --sums-file to verify it")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifest, err := util.Unzip(file, tempDir, reporter)
+	if err != nil {
+		return nil, err
+	}
+	return recordInstall(version, installationInfo, tempDir, manifest, filepath.Base(path), "", "file://"+filepath.ToSlash(path), verifier, aliases)
+}
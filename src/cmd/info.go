@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type infoCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	infoOpts   infoOpts
+}
+
+func newInfoCmd(globalOpts *globalOpts) *infoCmd {
+	cmd := &infoCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "info [version]",
+		Short: "Print where an installed version came from.",
+		Long: `Print where an installed version came from, as recorded when it was installed.
+
+When <version> is omitted, the active version is used. <version> can also be an alias given with nvmc install --alias.
+With --output json, the recorded metadata is printed as JSON.`,
+		Example: `$ nvmc info 18.2.0`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+
+	return cmd
+}
+
+func (c *infoCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var version string
+		var err error
+		if len(args) == 0 {
+			version, err = activeVersion()
+		} else {
+			version, err = resolveInstalledVersion(args[0], util.NativeTarget(), *c.globalOpts)
+		}
+		if err != nil {
+			return err
+		}
+		return info(version, *c.globalOpts, c.infoOpts)
+	}
+}
+
+func info(version string, globalOpts globalOpts, infoOpts infoOpts) error {
+	metadata, err := util.ReadInstallMetadata(version)
+	if err != nil {
+		return err
+	}
+
+	if globalOpts.output == "json" {
+		contents, err := json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(contents))
+		return nil
+	}
+
+	versionDir, err := util.GetVersionPath(version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("version:        " + metadata.Version)
+	fmt.Println("path:           " + filepath.Join(versionDir, metadata.Root))
+	if metadata.IsLegacy() {
+		fmt.Println("installed by an older nvmc, nothing else was recorded")
+		return nil
+	}
+
+	checksum := metadata.Checksum
+	if len(checksum) == 0 {
+		checksum = "not verified"
+	}
+	aliases := strings.Join(metadata.Aliases, ", ")
+	if len(aliases) == 0 {
+		aliases = "none"
+	}
+
+	fmt.Println("platform:       " + metadata.Target().String())
+	if metadata.IsLinked() {
+		fmt.Println("linked to:      " + metadata.LinkedPath)
+		fmt.Println("linked at:      " + metadata.InstalledAt.Local().Format(time.RFC3339))
+		return nil
+	}
+	fmt.Println("archive format: " + strings.TrimPrefix(string(metadata.ArchiveFormat), "."))
+	if metadata.FromSource {
+		fmt.Println("built with:     " + strings.Join(append([]string{"./configure"}, metadata.ConfigureFlags...), " "))
+	}
+	fmt.Println("source:         " + metadata.SourceUrl)
+	if len(metadata.Mirror) > 0 {
+		fmt.Println("mirror:         " + metadata.Mirror)
+	}
+	fmt.Println("checksum:       " + checksum)
+	fmt.Println("installed at:   " + metadata.InstalledAt.Local().Format(time.RFC3339))
+	fmt.Println("installed by:   nvmc " + metadata.NvmcVersion)
+	fmt.Println("aliases:        " + aliases)
+	fmt.Println("files:          " + fmt.Sprint(len(metadata.Files)))
+
+	return nil
+}
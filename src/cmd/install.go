@@ -2,161 +2,565 @@ package cmd
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
-	"fmt"
+	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
-	"io"
 	"io/fs"
 	"nvmc/util"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
 type installCmd struct {
 	command     *cobra.Command
 	installOpts installOpts
-	globalOpts  globalOpts
+	globalOpts  *globalOpts
 }
 
-func newInstallCmd(globalOpts globalOpts) *installCmd {
+func newInstallCmd(globalOpts *globalOpts) *installCmd {
 	cmd := &installCmd{}
 	cmd.command = &cobra.Command{
-		Use:   "install <version>",
+		Use:   "install [version]",
 		Short: "Download and install <version>.",
+		Long: `Download and install <version>.
+
+When <version> is omitted, it is read from the nearest .nvmrc, .node-version, or package.json (volta.node or
+engines.node) in the working directory or one of its parents.
+
+<version> can be an exact version (18.2.0), a partial version (18, 18.2), a semver range (^18.2, ">=20 <22"),
+or one of latest, lts, lts/* and lts/<codename>. Anything other than an exact version is resolved to the newest
+matching version available from the download URL.
+
+With --platform, --arch or --libc, the build for another platform, arch or C library is installed as
+<version>-<platform>-<arch>[-musl], e.g. v18.2.0-linux-x64-musl, next to the build for the current os and arch.
+Use that name, or the same options, with use and exec.
+
+With --from-file, an archive that was already downloaded is installed instead, e.g. on a machine without access to
+the download URL. The version and target are read from the name of the archive, so it must keep the name it was
+published with. Pass --checksum or --sums-file to verify it.`,
 		Example: `# Install version 18.2.0 and set it as active.
-$ nvmc install 18.2.0 --use`,
-		Args: cobra.ExactArgs(1),
+$ nvmc install 18.2.0 --use
+
+# Install the newest LTS version.
+$ nvmc install lts
+
+# Install the version from .nvmrc.
+$ nvmc install
+
+# Install the x64 build of 18.2.0 next to the build for the current arch.
+$ nvmc install 18.2.0 --arch x64
+
+# Install the musl build for Alpine Linux from unofficial-builds.nodejs.org.
+$ nvmc install 20 --libc musl
+
+# Build 18.2.0 from source, on a platform without a prebuilt archive.
+$ nvmc install 18.2.0 --from-source --jobs 4 --configure-flag=--with-intl=small-icu
+
+# Install an archive that was already downloaded, verifying it against the sums file published with it.
+$ nvmc install --from-file ./node-v18.2.0-linux-x64.tar.xz --sums-file ./SHASUMS256.txt
+
+# Install version 20.11.0 with the global npm packages of 18.2.0.
+$ nvmc install 20.11.0 --reinstall-packages-from=18.2.0`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: cmd.run(),
 	}
 
 	cmd.globalOpts = globalOpts
 	cmd.command.Flags().BoolVar(&cmd.installOpts.skipChecksumValidation, "skip-checksum-validation", defaultInstallOpts.skipChecksumValidation, "Skip checksum validation after downloading.")
 	cmd.command.Flags().BoolVar(&cmd.installOpts.use, "use", defaultInstallOpts.use, "After installing, set the installed <version> as active. (same as: nvmc use <version>).")
+	cmd.command.Flags().StringSliceVar(&cmd.installOpts.aliases, "alias", defaultInstallOpts.aliases, "Name that can be used instead of <version> with use, uninstall, shell, exec and run. Can be repeated.")
+	cmd.command.Flags().BoolVar(&cmd.installOpts.reinstall, "reinstall", defaultInstallOpts.reinstall, "Replace <version> when it is already installed, keeping its global npm packages and aliases.")
+	cmd.command.Flags().StringVar(&cmd.installOpts.reinstallPackagesFrom, "reinstall-packages-from", defaultInstallOpts.reinstallPackagesFrom, "Installed version whose global npm packages are installed into <version> with its npm.")
+	cmd.command.Flags().BoolVar(&cmd.installOpts.fromSource, "from-source", defaultInstallOpts.fromSource, "Build <version> from source with ./configure and make, for platforms without a prebuilt archive.")
+	cmd.command.Flags().StringArrayVar(&cmd.installOpts.configureFlags, "configure-flag", defaultInstallOpts.configureFlags, "Flag passed to ./configure when building from source, e.g. --with-intl=small-icu. Can be repeated.")
+	cmd.command.Flags().IntVar(&cmd.installOpts.jobs, "jobs", defaultInstallOpts.jobs, "Number of jobs make runs in parallel when building from source, defaults to the number of CPUs.")
+	cmd.command.Flags().StringVar(&cmd.installOpts.fromFile, "from-file", defaultInstallOpts.fromFile, "Install a node archive that was already downloaded, e.g. node-v18.2.0-linux-x64.tar.xz, instead of downloading it.")
+	cmd.command.Flags().StringVar(&cmd.installOpts.checksum, "checksum", defaultInstallOpts.checksum, "Checksum of the archive of --from-file, as <algorithm>:<hex> or the hex checksum of --checksum-algorithm.")
+	cmd.command.Flags().StringVar(&cmd.installOpts.sumsFile, "sums-file", defaultInstallOpts.sumsFile, "SHASUMS256.txt, or another sums file of --checksum-algorithm, that lists the archive of --from-file.")
+	addTargetFlags(cmd.command.Flags(), &cmd.installOpts.target)
 
 	return cmd
 }
 
 func (c *installCmd) run() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		version := args[0]
-		return install(version, c.globalOpts, c.installOpts)
+		if len(c.installOpts.fromFile) > 0 {
+			version, err := fileVersion(args, &c.installOpts)
+			if err != nil {
+				return err
+			}
+			return install(version, *c.globalOpts, c.installOpts)
+		}
+
+		expression, err := versionExpression(args)
+		if err != nil {
+			return err
+		}
+		target, err := c.installOpts.target.target()
+		if err != nil {
+			return err
+		}
+		version, err := resolveRemoteVersion(expression, target, *c.globalOpts)
+		if err != nil {
+			return err
+		}
+		return install(version, *c.globalOpts, c.installOpts)
 	}
 }
 
 func install(version string, globalOpts globalOpts, installOpts installOpts) error {
+	reporter := globalOpts.reporter()
 	version, err := util.NormalizeVersion(version)
 	if err != nil {
 		return err
 	}
 
-	installationInfo, err := util.GetInstallationInfo(version)
+	target, err := installOpts.target.target()
+	if err != nil {
+		return err
+	}
+	globalOpts = globalOpts.forTarget(target)
+	installationInfo, err := util.GetInstallationInfo(version, target)
 	if err != nil {
 		return err
 	}
 
+	// The name of the installation, which is the version unless another platform, arch or libc was requested.
+	name := util.InstallName(version, target)
 	// TODO: Validate the version
-	versionDir, err := util.GetVersionPath(version)
+	versionDir, err := util.GetVersionPath(name)
+	if err != nil {
+		return err
+	}
+
+	var packagesFrom string
+	if len(installOpts.reinstallPackagesFrom) > 0 {
+		packagesFrom, err = resolveInstalledVersion(installOpts.reinstallPackagesFrom, util.NativeTarget(), globalOpts)
+		if err != nil {
+			return err
+		}
+		if packagesFrom == name {
+			return errors.New("unable to reinstall the global npm packages of " + name + " into itself")
+		}
+	}
+
+	lock, err := lockVersion(name, reporter)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
+	var previous *util.InstallMetadata
 	if _, err := os.Stat(versionDir); err == nil {
-		return errors.New("requested installation " + version + " already exists, run nvmc uninstall <version> to remove the existing installation")
+		if !installOpts.reinstall {
+			return errors.New("requested installation " + name + " already exists, use --reinstall to replace it, or run nvmc uninstall <version> to remove the existing installation")
+		}
+		if previous, err = util.ReadInstallMetadata(name); err != nil {
+			return err
+		}
 	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	if err := validateAliases(name, installOpts.aliases); err != nil {
+		return err
+	}
+	aliases := installOpts.aliases
+	if previous != nil {
+		aliases = slices.Clone(previous.Aliases)
+		for _, alias := range installOpts.aliases {
+			if !slices.Contains(aliases, alias) {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
 
-	tempDir, err := os.MkdirTemp("", "nvmc-temp-"+version)
+	tempDir, err := stageVersion(name)
 	if err != nil {
 		return err
 	}
 	defer os.RemoveAll(tempDir)
 
-	tempZipFile, err := os.Create(filepath.Join(tempDir, installationInfo.FileNameWithExtension))
+	var metadata *util.InstallMetadata
+	switch {
+	case len(installOpts.fromFile) > 0:
+		metadata, err = stageFileInstall(version, installationInfo, tempDir, installOpts, aliases, globalOpts, reporter)
+	case installOpts.fromSource:
+		metadata, err = stageSourceInstall(version, installationInfo, tempDir, installOpts.skipChecksumValidation, installOpts.configureFlags, installOpts.jobs, aliases, globalOpts, reporter)
+	default:
+		metadata, err = stageInstall(version, installationInfo, tempDir, installOpts.skipChecksumValidation, aliases, globalOpts, reporter)
+	}
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tempZipFile.Name())
 
-	if err := util.Download(globalOpts.downloadUrl+"/"+version+"/"+installationInfo.FileNameWithExtension, tempZipFile); err != nil {
+	if previous != nil {
+		if err := replaceInstall(name, previous, tempDir, metadata, reporter); err != nil {
+			return err
+		}
+	} else if err := moveStagedVersion(tempDir, versionDir); err != nil {
 		return err
 	}
-	if _, err := tempZipFile.Seek(0, io.SeekStart); err != nil {
-		return err
+
+	if _, err := currentVersion(); err != nil && !installOpts.skipAutoUse {
+		reporter.Message("there is not a current node version activated, will activate " + name)
+		installOpts.use = true
 	}
 
-	if !installOpts.skipChecksumValidation {
-		tempChecksumFile, err := os.Create(filepath.Join(tempDir, "SHASUMS256.txt"))
-		if err != nil {
+	if installOpts.use {
+		if err := use(name, reporter); err != nil {
 			return err
 		}
-		defer os.Remove(tempChecksumFile.Name())
+	}
 
-		if err := util.Download(globalOpts.downloadUrl+"/"+version+"/SHASUMS256.txt", tempChecksumFile); err != nil {
-			return err
+	if len(packagesFrom) > 0 {
+		if err := reinstallGlobalPackages(packagesFrom, name, reporter); err != nil {
+			return errors.New("installed " + name + ", but " + err.Error())
 		}
-		if _, err := tempChecksumFile.Seek(0, io.SeekStart); err != nil {
-			return err
+	}
+
+	reporter.Message("successfully installed " + name)
+	return nil
+}
+
+// stageInstall downloads version and extracts it into tempDir, with the install.json that records where it came from.
+func stageInstall(version string, installationInfo *util.InstallationInfo, tempDir string, skipChecksumValidation bool, aliases []string, globalOpts globalOpts, reporter util.Reporter) (*util.InstallMetadata, error) {
+	formats, err := archiveFormats(version, installationInfo.Target(), globalOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var verifier *util.ChecksumVerifier
+	if !skipChecksumValidation {
+		verifier, err = fetchChecksumVerifier(version, installationInfo, formats, globalOpts, reporter)
+		if errors.Is(err, util.ErrChecksumNotFound) {
+			return nil, errors.New("refusing to install " + version + " without verifying it, " + err.Error() + ", use --skip-checksum-validation to install it anyway")
+		} else if err != nil {
+			return nil, err
 		}
+	}
 
-		fileBuf := new(bytes.Buffer)
-		if _, err = fileBuf.ReadFrom(tempChecksumFile); err != nil {
-			return err
+	manifest, fileName, err := downloadAndUnzip(version, installationInfo, formats, verifier, tempDir, globalOpts, reporter)
+	if errors.Is(err, util.ErrNotFound) {
+		return nil, errors.New("version " + version + " was not found on the mirror " + globalOpts.downloadUrl + ", " + err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+	return recordInstall(version, installationInfo, tempDir, manifest, fileName, globalOpts.downloadUrl, globalOpts.downloadUrl+"/"+version+"/"+fileName, verifier, aliases)
+}
+
+// recordInstall checks that the archive fileName of version, extracted into tempDir, has the expected layout, and
+// writes the install.json that records where it came from. verifier is nil when checksum validation was skipped.
+func recordInstall(version string, installationInfo *util.InstallationInfo, tempDir string, manifest *util.Manifest, fileName string, mirror string, sourceUrl string, verifier *util.ChecksumVerifier, aliases []string) (*util.InstallMetadata, error) {
+	if manifest.Root != filepath.Join(tempDir, installationInfo.FileNameWithoutExtension) {
+		return nil, errors.New("unexpected archive layout, the files of " + version + " are not in the directory " + installationInfo.FileNameWithoutExtension)
+	}
+	hashes, err := util.HashFiles(manifest.Root, manifest.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &util.InstallMetadata{
+		Version:     version,
+		Root:        installationInfo.FileNameWithoutExtension,
+		Platform:    installationInfo.Platform,
+		Arch:        installationInfo.Arch,
+		Libc:        installationInfo.Libc,
+		Mirror:      mirror,
+		SourceUrl:   sourceUrl,
+		InstalledAt: time.Now().UTC(),
+		NvmcVersion: util.VERSION,
+		Aliases:     aliases,
+		Files:       manifest.Files,
+		FileHashes:  hashes,
+	}
+	metadata.ArchiveFormat = util.ArchiveFormat(strings.TrimPrefix(fileName, installationInfo.FileNameWithoutExtension))
+	if verifier != nil {
+		metadata.Checksum = string(verifier.Algorithm) + ":" + verifier.Expected
+	}
+	if err := util.WriteInstallMetadata(tempDir, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// validateAliases returns an error when an alias could be mistaken for a version, or is an alias of another installed
+// version than name.
+func validateAliases(name string, aliases []string) error {
+	installed, _, err := util.ListInstallMetadata()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, alias := range aliases {
+		if len(alias) == 0 || util.IsExactVersion(alias) || util.IsLtsAlias(alias) || alias == "latest" || alias == "node" {
+			return errors.New("invalid alias " + alias + ", it can not be a version or a keyword")
 		}
-		fileContents := fileBuf.String()
-
-		checksums := strings.Split(fileContents, "\n")
-		for _, checksumLine := range checksums {
-			if strings.HasSuffix(checksumLine, installationInfo.FileNameWithExtension) {
-				checksum, found := strings.CutSuffix(checksumLine, " "+installationInfo.FileNameWithExtension)
-				if !found {
-					return errors.New("unable to verify checksum")
-				}
-				hash := sha256.New()
-				if _, err := io.Copy(hash, tempZipFile); err != nil {
-					return err
-				}
-				if _, err := tempZipFile.Seek(0, io.SeekStart); err != nil {
-					return err
-				}
-				generatedChecksum := hex.EncodeToString(hash.Sum(nil))
-				if strings.TrimSpace(checksum) != strings.TrimSpace(generatedChecksum) {
-					return errors.New("checksum does not match")
-				}
+		if _, err := semver.NewConstraint(alias); err == nil {
+			return errors.New("invalid alias " + alias + ", it can not be a version range")
+		}
+		for _, metadata := range installed {
+			if metadata.Name() != name && slices.Contains(metadata.Aliases, alias) {
+				return errors.New("alias " + alias + " is already used by " + metadata.Name())
 			}
 		}
 	}
+	return nil
+}
 
-	_, err = util.Unzip(tempZipFile, tempDir)
+// reinstallGlobalPackages installs the global npm packages of from into version, using the npm of version. Packages
+// are installed at their newest version, as the versions installed in from might not support version. Packages
+// installed with npm link are linked again.
+func reinstallGlobalPackages(from string, version string, reporter util.Reporter) error {
+	fromMetadata, err := util.ReadInstallMetadata(from)
+	if err != nil {
+		return err
+	}
+	fromDir, err := util.GetVersionPath(from)
 	if err != nil {
 		return err
 	}
+	packages, err := util.ListGlobalPackages(filepath.Join(fromDir, fromMetadata.Root))
+	if err != nil {
+		return errors.New("unable to list the global npm packages of " + from + ": " + err.Error())
+	}
+	if len(packages) == 0 {
+		reporter.Message(from + " has no global npm packages to reinstall")
+		return nil
+	}
 
-	versionsDir, err := util.GetVersionsPath()
+	activation, err := shellActivation(version, "")
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(versionsDir, fs.ModePerm); err != nil {
+	args, names := []string{"install", "--global"}, make([]string, 0, len(packages))
+	for _, globalPackage := range packages {
+		if len(globalPackage.Link) > 0 {
+			args = append(args, globalPackage.Link)
+		} else {
+			args = append(args, globalPackage.Name)
+		}
+		names = append(names, globalPackage.Name)
+	}
+	reporter.Message("reinstalling the global npm packages of " + from + ": " + strings.Join(names, ", "))
+
+	npm := filepath.Join(activation.BinPath, "npm")
+	if runtime.GOOS == "windows" {
+		npm += ".cmd"
+	}
+	// npm writes its output to stderr, stdout only has the status messages of nvmc.
+	child := exec.Command(npm, args...)
+	child.Env = append(os.Environ(), "PATH="+activation.Path)
+	child.Stdout = os.Stderr
+	child.Stderr = os.Stderr
+	if err := child.Run(); err != nil {
+		return errors.New("unable to reinstall the global npm packages of " + from + ", npm install failed: " + err.Error())
+	}
+	return nil
+}
+
+// lockVersion returns the lock of version, which is held while version is installed or uninstalled. A version that
+// was moved aside by a repair that did not finish is restored first.
+func lockVersion(version string, reporter util.Reporter) (*util.FileLock, error) {
+	lock, err := util.LockFile(util.VersionLockName(version), func() {
+		reporter.Message("waiting for another nvmc process to finish with " + version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	restored, err := util.RestorePreviousVersion(version)
+	if err != nil {
+		_ = lock.Unlock()
+		return nil, err
+	}
+	if restored {
+		reporter.Message("restored " + version + ", which was moved aside by a repair that did not finish")
+	}
+	return lock, nil
+}
+
+// stageVersion returns a new directory to extract version to. The caller must hold the lock of version, so any
+// other staging directory of version was left behind by an nvmc process that was killed, and is removed.
+func stageVersion(version string) (string, error) {
+	stagingDir, err := util.GetStagingPath(version)
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(stagingDir, fs.ModePerm); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(stagingDir, "install-")
+}
+
+// moveStagedVersion moves the version staged in tempDir, see stageVersion, to versionDir.
+func moveStagedVersion(tempDir string, versionDir string) error {
+	if err := os.MkdirAll(filepath.Dir(versionDir), fs.ModePerm); err != nil {
 		return err
 	}
 	if err := os.Rename(tempDir, versionDir); err != nil {
 		return err
 	}
+	// Remove the staging directory of the version, ignoring the error when another staging directory is in it.
+	_ = os.Remove(filepath.Dir(tempDir))
+	return nil
+}
 
-	if _, err := currentVersion(); err != nil {
-		fmt.Println("there is not a current node version activated, will activate " + version)
-		installOpts.use = true
+// archiveFormats returns the archive formats to try for version, starting with the preferred format. Fails when
+// the files list of the index shows that version does not publish an archive for target.
+func archiveFormats(version string, target util.Target, globalOpts globalOpts) ([]util.ArchiveFormat, error) {
+	preferred, err := util.ParseArchiveFormat(globalOpts.archiveFormat)
+	if err != nil {
+		return nil, err
 	}
 
-	if installOpts.use {
-		if err := use(version); err != nil {
-			return err
+	// The index is optional, a mirror might only publish the versions.
+	entries, err := util.FetchIndex(globalOpts.downloadUrl, globalOpts.downloadOptions())
+	if err == nil {
+		for _, entry := range entries {
+			if entry.Version != version || entry.HasArchive(target) {
+				continue
+			}
+			if target.Platform == "darwin" && target.Arch == "arm64" {
+				// Versions before 16 were only built for x64, which runs on arm64 with Rosetta.
+				return nil, errors.New("version " + version + " does not publish an archive for " + target.String() + ", use --arch x64 to install the x64 build, which runs with Rosetta")
+			}
+			return nil, errors.New("version " + version + " does not publish an archive for " + target.String() + ", use --from-source to build it")
 		}
 	}
 
-	fmt.Printf("successfully installed %s\n", version)
-	return nil
+	return util.ArchiveFormats(preferred, target), nil
+}
+
+// fetchChecksumVerifier returns a verifier for the first of formats that the sums file of version lists, failing
+// with util.ErrChecksumNotFound when the sums file lists none of them.
+func fetchChecksumVerifier(version string, installationInfo *util.InstallationInfo, formats []util.ArchiveFormat, globalOpts globalOpts, reporter util.Reporter) (*util.ChecksumVerifier, error) {
+	algorithm, err := util.ParseChecksumAlgorithm(globalOpts.checksumAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	fileBuf := new(bytes.Buffer)
+	if err := util.Download(globalOpts.downloadUrl+"/"+version+"/"+algorithm.SumsFileName(), fileBuf, globalOpts.downloadOptions()); err != nil {
+		return nil, err
+	}
+	contents, err := verifySumsSignature(version, algorithm.SumsFileName(), fileBuf.Bytes(), globalOpts, reporter)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := util.ParseSums(contents, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if globalOpts.offline {
+		// Only the archives imported with nvmc bundle import are available, which might not be the preferred format.
+		for _, format := range formats {
+			verifier, err := util.NewChecksumVerifier(sums, installationInfo.FileName(format), algorithm)
+			if err == nil && util.IsCached(verifier) {
+				return verifier, nil
+			}
+		}
+	}
+	return util.SelectArchive(sums, installationInfo.FileNameWithoutExtension, formats, algorithm)
+}
+
+// downloadAndUnzip extracts the archive of version into tempDir, returning the name of the archive. An archive with a verifier is verified, stored in
+// the cache and extracted while it is downloaded, otherwise the first of formats that exists is downloaded to a
+// temporary file and extracted from there.
+func downloadAndUnzip(version string, installationInfo *util.InstallationInfo, formats []util.ArchiveFormat, verifier *util.ChecksumVerifier, tempDir string, globalOpts globalOpts, reporter util.Reporter) (*util.Manifest, string, error) {
+	downloadOptions := globalOpts.downloadOptions()
+	downloadOptions.Reporter = reporter
+	if verifier != nil {
+		manifest, err := util.CacheDownloadAndUnzip(globalOpts.downloadUrl+"/"+version+"/"+verifier.Name, verifier, tempDir, downloadOptions)
+		return manifest, verifier.Name, err
+	}
+
+	var err error
+	for _, format := range formats {
+		fileName := installationInfo.FileName(format)
+		var manifest *util.Manifest
+		manifest, err = downloadArchiveAndUnzip(version, fileName, tempDir, globalOpts, downloadOptions, reporter)
+		if err == nil {
+			return manifest, fileName, nil
+		} else if !errors.Is(err, util.ErrNotFound) {
+			return nil, "", err
+		}
+	}
+
+	return nil, "", err
+}
+
+// downloadArchiveAndUnzip downloads the archive fileName of version to a temporary file, which is removed before
+// returning, and extracts it into tempDir. A missing archive is reported as util.ErrNotFound.
+func downloadArchiveAndUnzip(version string, fileName string, tempDir string, globalOpts globalOpts, downloadOptions util.DownloadOptions, reporter util.Reporter) (*util.Manifest, error) {
+	tempFile, err := os.CreateTemp("", "nvmc-download-*-"+fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if err := util.DownloadFile(globalOpts.downloadUrl+"/"+version+"/"+fileName, tempFile.Name(), nil, downloadOptions); err != nil {
+		return nil, err
+	}
+	return util.Unzip(tempFile, tempDir, reporter)
+}
+
+// verifySumsSignature verifies the sums file, e.g. SHASUMS256.txt, against SHASUMS256.txt.sig, or SHASUMS256.txt.asc
+// for releases that only publish a clear signed file, following the --verify-signature policy. Returns the sums to trust.
+// Warnings of the optional policy are sent to reporter.
+func verifySumsSignature(version string, sumsFileName string, sums []byte, globalOpts globalOpts, reporter util.Reporter) ([]byte, error) {
+	policy, err := util.ParseSignaturePolicy(globalOpts.verifySignature)
+	if err != nil || policy == util.SignatureOff {
+		return sums, err
+	}
+
+	keyring, err := util.LoadKeyring(globalOpts.keyring)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyring) == 0 {
+		if policy == util.SignatureRequired {
+			return nil, errors.New("unable to verify the signature of " + sumsFileName + ", there are no trusted keys, set --keyring")
+		}
+		reporter.Message("warning: not verifying the signature of " + sumsFileName + ", there are no trusted keys")
+		return sums, nil
+	}
+
+	sumsUrl := globalOpts.downloadUrl + "/" + version + "/" + sumsFileName
+	signature := new(bytes.Buffer)
+	err = util.Download(sumsUrl+".sig", signature, globalOpts.downloadOptions())
+	if err == nil {
+		if _, err := util.VerifyDetachedSignature(sums, signature.Bytes(), keyring); err != nil {
+			return nil, errors.New("the signature of " + sumsFileName + " is not valid: " + err.Error())
+		}
+		return sums, nil
+	} else if !errors.Is(err, util.ErrNotFound) {
+		return nil, err
+	}
+
+	clearSigned := new(bytes.Buffer)
+	err = util.Download(sumsUrl+".asc", clearSigned, globalOpts.downloadOptions())
+	if err == nil {
+		signedSums, _, err := util.VerifyClearSigned(clearSigned.Bytes(), keyring)
+		if err != nil {
+			return nil, errors.New("the signature of " + sumsFileName + ".asc is not valid: " + err.Error())
+		}
+		return signedSums, nil
+	} else if !errors.Is(err, util.ErrNotFound) {
+		return nil, err
+	}
+
+	if policy == util.SignatureRequired {
+		return nil, errors.New(sumsUrl + " is not signed, neither " + sumsFileName + ".sig nor " + sumsFileName + ".asc exist")
+	}
+	reporter.Message("warning: " + sumsUrl + " is not signed, neither " + sumsFileName + ".sig nor " + sumsFileName + ".asc exist")
+	return sums, nil
 }
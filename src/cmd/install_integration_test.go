@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"nvmc/util"
 	"os"
@@ -29,10 +30,14 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func runBinary(args ...string) ([]byte, error) {
+// runBinary returns stdout and stderr separately, progress is written to stderr and depends on timing.
+func runBinary(args ...string) ([]byte, []byte, error) {
 	cmd := exec.Command(binaryPath, args...)
 	cmd.Env = append(os.Environ(), "GOCOVERDIR=.coverdata")
-	return cmd.CombinedOutput()
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	output, err := cmd.Output()
+	return output, stderr.Bytes(), err
 }
 
 func TestInstall(t *testing.T) {
@@ -48,9 +53,9 @@ func TestInstall(t *testing.T) {
 	for _, tt := range tests {
 		util.IntegrationTest(t)
 		t.Run(tt.name, func(t *testing.T) {
-			output, err := runBinary(tt.args...)
+			output, stderr, err := runBinary(tt.args...)
 			if err != nil {
-				t.Fatalf("Output:%v\nError:%v", string(output), err)
+				t.Fatalf("Output:%v\nStderr:%v\nError:%v", string(output), string(stderr), err)
 			}
 
 			actual := string(output)
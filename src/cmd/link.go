@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type linkCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	linkOpts   linkOpts
+}
+
+func newLinkCmd(globalOpts *globalOpts) *linkCmd {
+	cmd := &linkCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "link <name> <path>",
+		Short: "Register a node build from <path> as the version <name>.",
+		Long: `Register a node build from <path> as the version <name>, e.g. a debug build of a node checkout.
+
+<path> is either a prefix with a bin directory, as written by make install, or the directory of the node executable,
+e.g. out/Release. The build is not copied, so rebuilding it updates the version. <name> can then be used like any
+other version with use, exec, shell, run, list and info. nvmc uninstall <name> removes the link, not the build.`,
+		Example: `# Register the release build of a node checkout as debug, and set it as active.
+$ nvmc link debug ~/src/node/out/Release --use
+
+# Register a build installed with make install.
+$ nvmc link custom /opt/node-custom`,
+		Args: cobra.ExactArgs(2),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.linkOpts.use, "use", defaultLinkOpts.use, "After linking, set <name> as active. (same as: nvmc use <name>).")
+
+	return cmd
+}
+
+func (c *linkCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return link(args[0], args[1], *c.globalOpts, c.linkOpts)
+	}
+}
+
+func link(name string, dir string, globalOpts globalOpts, linkOpts linkOpts) error {
+	reporter := globalOpts.reporter()
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return errors.New("invalid name " + name + ", it can not be a path")
+	}
+	if err := validateAliases(name, []string{name}); err != nil {
+		return errors.New("invalid name " + name + ", " + strings.TrimPrefix(err.Error(), "invalid alias "+name+", "))
+	}
+
+	node, _, err := util.FindLinkedNode(dir)
+	if err != nil {
+		return err
+	}
+	version, err := linkedVersion(node)
+	if err != nil {
+		return err
+	}
+
+	versionDir, err := util.GetVersionPath(name)
+	if err != nil {
+		return err
+	}
+	lock, err := lockVersion(name, reporter)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if _, err := os.Stat(versionDir); err == nil {
+		return errors.New("version " + name + " already exists, run nvmc uninstall " + name + " to remove it first")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tempDir, err := stageVersion(name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	metadata, err := util.StageLink(tempDir, name, dir, version)
+	if err != nil {
+		return err
+	}
+	if err := moveStagedVersion(tempDir, versionDir); err != nil {
+		return err
+	}
+
+	reporter.Message("linked " + name + " (" + version + ") to " + metadata.LinkedPath)
+	if linkOpts.use {
+		return use(name, reporter)
+	}
+	return nil
+}
+
+// linkedVersion returns the version that the node executable reports.
+func linkedVersion(node string) (string, error) {
+	output, err := exec.Command(node, "--version").Output()
+	if err != nil {
+		return "", errors.New("unable to run " + node + " --version: " + err.Error())
+	}
+	version := strings.TrimSpace(string(output))
+	if !util.IsExactVersion(version) {
+		return "", errors.New(node + " --version printed " + version + ", which is not a version")
+	}
+	return util.NormalizeVersion(version)
+}
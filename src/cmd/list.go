@@ -3,26 +3,25 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
 	"nvmc/util"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
 )
 
 type listCmd struct {
 	command    *cobra.Command
-	globalOpts globalOpts
+	globalOpts *globalOpts
 	listOpts   listOpts
 }
 
-func newListCmd(globalOpts globalOpts) *listCmd {
+func newListCmd(globalOpts *globalOpts) *listCmd {
 	cmd := &listCmd{}
 	cmd.command = &cobra.Command{
 		Aliases: []string{"ls"},
 		Use:     "list",
-		Short:   "List all installed node versions.",
+		Short:   "List all installed node versions, with the platform and arch they were installed for.",
 		Example: `$ nvmc list`,
 		Args:    cobra.ExactArgs(0),
 		RunE:    cmd.run(),
@@ -40,67 +39,61 @@ func (c *listCmd) run() func(cmd *cobra.Command, args []string) error {
 }
 
 func list() error {
-	versions, err := retrieveVersions()
-	if err != nil {
+	installed, invalid, err := util.ListInstallMetadata()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
-	} else if len(versions) == 0 {
+	} else if len(installed)+len(invalid) == 0 {
 		return errors.New("no versions installed")
 	}
 
-	current, _ := currentVersion()
+	current, _ := activeVersion()
 
-	for _, version := range versions {
-		if current == version {
-			version = version + " (current)"
+	for _, metadata := range installed {
+		line := metadata.Name() + " (" + metadata.Target().String() + ")"
+		if metadata.IsLinked() {
+			line = line + " (linked to " + metadata.LinkedPath + ")"
+		}
+		if len(metadata.Aliases) > 0 {
+			line = line + " (alias " + strings.Join(metadata.Aliases, ", ") + ")"
+		}
+		if current == metadata.Name() {
+			line = line + " (current)"
 		}
-		fmt.Println(version)
+		fmt.Println(line)
+	}
+	for _, name := range invalid {
+		fmt.Println("Unable to parse " + name)
 	}
 
 	return nil
 }
 
+// retrieveVersions returns the versions installed for the current os and arch, sorted by version. Linked versions
+// and versions installed for another platform, arch or libc are not included.
 func retrieveVersions() ([]string, error) {
 	versions := make([]string, 0)
-	nvmcVersionsDir, err := util.GetVersionsPath()
+	installed, _, err := util.ListInstallMetadata()
 	if err != nil {
 		return versions, err
 	}
 
-	dirList, err := os.ReadDir(nvmcVersionsDir)
-	if err != nil {
-		return versions, err
-	}
-
-	for _, dirEntry := range dirList {
-		if dirEntry.IsDir() && strings.HasPrefix(dirEntry.Name(), "v") {
-			versions = append(versions, dirEntry.Name())
-		}
-	}
-
-	semverVersions := make([]*semver.Version, 0)
-	parseFailures := make([]string, 0)
-	for _, version := range versions {
-		semverVersion, err := semver.NewVersion(version)
-		if err != nil {
-			parseFailures = append(parseFailures, version)
-		} else {
-			semverVersions = append(semverVersions, semverVersion)
+	for _, metadata := range installed {
+		if !metadata.IsLinked() && metadata.Target().IsNative() {
+			versions = append(versions, metadata.Version)
 		}
 	}
+	return versions, nil
+}
 
-	sort.Sort(semver.Collection(semverVersions))
-
-	versions = make([]string, len(semverVersions)+len(parseFailures))
-	for i, semverVersion := range semverVersions {
-		versions[i] = "v" + semverVersion.String()
+// activeVersion returns the version activated for this shell by nvmc shell, or the global current version.
+func activeVersion() (string, error) {
+	if shellVersion := os.Getenv(util.ShellVersionEnv); len(shellVersion) > 0 {
+		return shellVersion, nil
 	}
-	for i, parseFailure := range parseFailures {
-		versions[i+len(semverVersions)] = "Unable to parse " + parseFailure
-	}
-
-	return versions, nil
+	return currentVersion()
 }
 
+// currentVersion returns the version the global symlink points to.
 func currentVersion() (string, error) {
 	nodeSymLink, err := util.GetSymLinkPath()
 	if err != nil {
@@ -112,7 +105,17 @@ func currentVersion() (string, error) {
 	} else if err != nil {
 		return "", err
 	}
-	pathParts := strings.Split(version, string(os.PathSeparator))
 
-	return pathParts[len(pathParts)-2], nil
+	// The symlink targets a directory nested inside the version directory, e.g. versions/<version>/<archive>/bin.
+	versionsDir, err := util.GetVersionsPath()
+	if err != nil {
+		return "", err
+	}
+	relativePath, err := filepath.Rel(versionsDir, version)
+	if err != nil || relativePath == "." || strings.HasPrefix(relativePath, "..") {
+		return "", errors.New("Current version is not an installed version. Path: " + version)
+	}
+	pathParts := strings.Split(relativePath, string(os.PathSeparator))
+
+	return pathParts[0], nil
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"strings"
+)
+
+type lsRemoteCmd struct {
+	command      *cobra.Command
+	globalOpts   *globalOpts
+	lsRemoteOpts lsRemoteOpts
+}
+
+func newLsRemoteCmd(globalOpts *globalOpts) *lsRemoteCmd {
+	cmd := &lsRemoteCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "ls-remote",
+		Short: "List all node versions available to install.",
+		Example: `$ nvmc ls-remote
+
+# List versions available from a mirror.
+$ nvmc ls-remote --download-url https://mirror.example.com/node`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.lsRemoteOpts.lts, "lts", defaultLsRemoteOpts.lts, "Only list LTS versions.")
+
+	return cmd
+}
+
+func (c *lsRemoteCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return lsRemote(*c.globalOpts, c.lsRemoteOpts)
+	}
+}
+
+func lsRemote(globalOpts globalOpts, lsRemoteOpts lsRemoteOpts) error {
+	entries, err := util.FetchIndex(globalOpts.downloadUrl, globalOpts.downloadOptions())
+	if err != nil {
+		return err
+	}
+
+	installedVersions, err := retrieveVersions()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	installed := make(map[string]bool, len(installedVersions))
+	for _, version := range installedVersions {
+		installed[version] = true
+	}
+
+	current, _ := activeVersion()
+
+	for _, entry := range entries {
+		if lsRemoteOpts.lts && entry.Lts == "" {
+			continue
+		}
+		version, err := util.NormalizeVersion(entry.Version)
+		if err != nil {
+			return err
+		}
+
+		line := version
+		if entry.Lts != "" {
+			line = line + " (lts/" + strings.ToLower(string(entry.Lts)) + ")"
+		}
+		if current == version {
+			line = line + " (current)"
+		} else if installed[version] {
+			line = line + " (installed)"
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
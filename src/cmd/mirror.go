@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"net"
+	"net/http"
+	"nvmc/util"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type mirrorCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	mirrorOpts mirrorOpts
+}
+
+func newMirrorCmd(globalOpts *globalOpts) *mirrorCmd {
+	cmd := &mirrorCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "mirror",
+		Short: "Maintain and serve a mirror of the download URL.",
+		Long: `Maintain and serve a mirror of the download URL.
+
+A mirror is a directory with the layout of nodejs.org/dist: index.json and index.tab, and a directory per version with
+its SHASUMS256.txt, signatures and archives. Point --download-url at a mirror served with nvmc mirror serve, or by
+any other HTTP server, to install from it.`,
+		Example: `# Mirror every version since 18 for linux and windows, run it again to download new versions.
+$ nvmc mirror sync ./node-mirror --filter '>=18' --platforms linux-x64,win-x64
+
+# Serve the mirror, and install from it on another host.
+$ nvmc mirror serve ./node-mirror --listen 0.0.0.0:8080
+$ nvmc install 20 --download-url http://mirror-host:8080`,
+		Args: cobra.ExactArgs(0),
+	}
+
+	cmd.globalOpts = globalOpts
+	syncCommand := &cobra.Command{
+		Use:   "sync <dir>",
+		Short: "Download the versions that <dir> does not have yet from the download URL.",
+		Long: `Download the versions that <dir> does not have yet from the download URL.
+
+Versions that are already in <dir> are skipped, unless --platforms adds a platform that they do not have yet.
+Archives that are already in <dir> and match SHASUMS256.txt are not downloaded again, and an interrupted download is
+resumed. The SHASUMS256.txt of a version is written after its archives, and index.json and index.tab only list the
+versions that have one, so the mirror can be served while it is synced.
+
+Platforms that are only published to unofficial-builds.nodejs.org, e.g. linux-x64-musl, are mirrored from there, to
+another directory than the platforms of nodejs.org.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return mirrorSync(args[0], *cmd.globalOpts, cmd.mirrorOpts)
+		},
+	}
+	syncCommand.Flags().StringVar(&cmd.mirrorOpts.filter, "filter", defaultMirrorOpts.filter, "Semver range of the versions to mirror, e.g. '>=18'. Defaults to every version.")
+	syncCommand.Flags().StringSliceVar(&cmd.mirrorOpts.platforms, "platforms", defaultMirrorOpts.platforms, "Platforms to mirror the archives of, e.g. linux-x64,win-x64,linux-x64-musl. Defaults to the current os and arch.")
+	cmd.command.AddCommand(syncCommand)
+	serveCommand := &cobra.Command{
+		Use:   "serve <dir>",
+		Short: "Serve the mirror in <dir> over HTTP.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return mirrorServe(args[0], *cmd.globalOpts, cmd.mirrorOpts)
+		},
+	}
+	serveCommand.Flags().StringVar(&cmd.mirrorOpts.listen, "listen", defaultMirrorOpts.listen, "Address to listen on, e.g. 0.0.0.0:8080 to serve other hosts.")
+	cmd.command.AddCommand(serveCommand)
+
+	return cmd
+}
+
+func mirrorSync(dir string, globalOpts globalOpts, mirrorOpts mirrorOpts) error {
+	reporter := globalOpts.reporter()
+	var constraint *semver.Constraints
+	if len(mirrorOpts.filter) > 0 {
+		var err error
+		if constraint, err = semver.NewConstraint(mirrorOpts.filter); err != nil {
+			return errors.New("invalid filter " + mirrorOpts.filter + ", must be a semver range like >=18")
+		}
+	}
+	targets := []util.Target{util.NativeTarget()}
+	if len(mirrorOpts.platforms) > 0 {
+		targets = targets[:0]
+		for _, platform := range mirrorOpts.platforms {
+			target, err := util.ParseTargetName(platform)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, target)
+		}
+	}
+	// The builds of unofficial targets, e.g. musl, are published to unofficial-builds with their own index and sums
+	// files, which cannot be merged with the ones of the download URL.
+	for _, target := range targets[1:] {
+		if globalOpts.forTarget(target).downloadUrl != globalOpts.forTarget(targets[0]).downloadUrl {
+			return errors.New("unable to mirror " + targets[0].String() + " and " + target.String() + " to the same directory, only one of them is published to " +
+				util.UnofficialBuildsUrl + ", sync them to separate directories")
+		}
+	}
+	globalOpts = globalOpts.forTarget(targets[0])
+	algorithm, err := util.ParseChecksumAlgorithm(globalOpts.checksumAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	lock, err := util.LockFile("mirror", func() {
+		reporter.Message("waiting for another nvmc process to finish syncing a mirror")
+	})
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	indexJson := new(bytes.Buffer)
+	if err := util.Download(globalOpts.downloadUrl+"/index.json", indexJson, globalOpts.downloadOptions()); err != nil {
+		return err
+	}
+	entries, err := util.ParseIndex(indexJson.Bytes())
+	if err != nil {
+		return err
+	}
+	// index.tab is optional, a mirror might only publish index.json.
+	var indexTab []byte
+	tabBuf := new(bytes.Buffer)
+	if err := util.Download(globalOpts.downloadUrl+"/index.tab", tabBuf, globalOpts.downloadOptions()); err == nil {
+		indexTab = tabBuf.Bytes()
+	} else if !errors.Is(err, util.ErrNotFound) {
+		return err
+	}
+
+	mirrored := mirroredVersion(dir, algorithm)
+	synced, downloaded := 0, 0
+	for _, entry := range entries {
+		version, err := semver.NewVersion(entry.Version)
+		if err != nil || (constraint != nil && !constraint.Check(version)) || !slices.ContainsFunc(targets, entry.HasArchive) {
+			continue
+		}
+		var count int
+		if count, err = mirrorVersion(dir, entry, targets, algorithm, globalOpts, reporter); err != nil {
+			err = errors.New("unable to sync " + entry.Version + ", " + err.Error())
+		}
+		downloaded += count
+		if mirrored(entry.Version) {
+			synced++
+		}
+		if err != nil {
+			// List the versions that were synced before failing.
+			_ = util.WriteMirrorIndex(dir, indexJson.Bytes(), indexTab, mirrored)
+			return err
+		}
+	}
+	if synced == 0 {
+		names := make([]string, 0, len(targets))
+		for _, target := range targets {
+			names = append(names, target.String())
+		}
+		return errors.New("no version of " + globalOpts.downloadUrl + " matching the filter has archives for " + strings.Join(names, ", "))
+	}
+	if err := util.WriteMirrorIndex(dir, indexJson.Bytes(), indexTab, mirrored); err != nil {
+		return err
+	}
+
+	reporter.Message("synced " + strconv.Itoa(synced) + " versions to " + dir + ", downloaded " + strconv.Itoa(downloaded) + " archives")
+	return nil
+}
+
+// mirrorVersion downloads the archives of the version of entry for targets into dir, returning the number of archives
+// that were downloaded. A version that was already synced is skipped when it has the archives of every target, without
+// downloading its sums file again. The sums file is not written when the version has no archive for targets.
+func mirrorVersion(dir string, entry util.IndexEntry, targets []util.Target, algorithm util.ChecksumAlgorithm, globalOpts globalOpts, reporter util.Reporter) (int, error) {
+	versionUrl := globalOpts.downloadUrl + "/" + entry.Version
+	versionDir := filepath.Join(dir, entry.Version)
+	sumsFileName := algorithm.SumsFileName()
+	if contents, err := os.ReadFile(filepath.Join(versionDir, sumsFileName)); err == nil {
+		if hasMirroredArchives(versionDir, contents, entry, targets, algorithm) {
+			return 0, nil
+		}
+	}
+
+	sumsBuf := new(bytes.Buffer)
+	if err := util.Download(versionUrl+"/"+sumsFileName, sumsBuf, globalOpts.downloadOptions()); err != nil {
+		return 0, err
+	}
+	contents, err := verifySumsSignature(entry.Version, sumsFileName, sumsBuf.Bytes(), globalOpts, reporter)
+	if err != nil {
+		return 0, err
+	}
+	verifiers, err := mirrorArchives(contents, entry, targets, algorithm)
+	if err != nil || len(verifiers) == 0 {
+		return 0, err
+	}
+
+	downloadOptions := globalOpts.downloadOptions()
+	downloadOptions.Reporter = reporter
+	downloaded := 0
+	for _, verifier := range verifiers {
+		archiveDownloaded, err := util.MirrorFile(versionUrl+"/"+verifier.Name, filepath.Join(versionDir, verifier.Name), verifier, downloadOptions)
+		if err != nil {
+			return downloaded, err
+		}
+		if archiveDownloaded {
+			downloaded++
+		}
+	}
+
+	// The sums file is written last, see mirroredVersion.
+	for _, fileName := range []string{sumsFileName + ".sig", sumsFileName + ".asc", sumsFileName} {
+		contents := sumsBuf
+		if fileName != sumsFileName {
+			contents = new(bytes.Buffer)
+			err := util.Download(versionUrl+"/"+fileName, contents, globalOpts.downloadOptions())
+			if errors.Is(err, util.ErrNotFound) {
+				continue
+			} else if err != nil {
+				return downloaded, err
+			}
+		}
+		if err := util.WriteFileAtomic(filepath.Join(versionDir, fileName), contents.Bytes()); err != nil {
+			return downloaded, err
+		}
+	}
+	return downloaded, nil
+}
+
+// mirrorArchives returns a verifier for each archive of targets that is listed in the sums file contents of the
+// version of entry. Archives that are not published for a target are skipped.
+func mirrorArchives(contents []byte, entry util.IndexEntry, targets []util.Target, algorithm util.ChecksumAlgorithm) ([]*util.ChecksumVerifier, error) {
+	sums, err := util.ParseSums(contents, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	verifiers := make([]*util.ChecksumVerifier, 0)
+	for _, target := range targets {
+		if !entry.HasArchive(target) {
+			continue
+		}
+		installationInfo, err := util.GetInstallationInfo(entry.Version, target)
+		if err != nil {
+			return nil, err
+		}
+		for _, format := range []util.ArchiveFormat{util.ArchiveTarGz, util.ArchiveTarXz, util.ArchiveZip} {
+			if verifier, err := util.NewChecksumVerifier(sums, installationInfo.FileName(format), algorithm); err == nil {
+				verifiers = append(verifiers, verifier)
+			}
+		}
+	}
+	return verifiers, nil
+}
+
+// hasMirroredArchives returns whether versionDir has the archives of targets that are listed in the sums file contents
+// of the version of entry, and the sums file lists an archive for each of them. Otherwise, a target was added since
+// the version was synced.
+func hasMirroredArchives(versionDir string, contents []byte, entry util.IndexEntry, targets []util.Target, algorithm util.ChecksumAlgorithm) bool {
+	for _, target := range targets {
+		verifiers, err := mirrorArchives(contents, entry, []util.Target{target}, algorithm)
+		if err != nil || (entry.HasArchive(target) && len(verifiers) == 0) {
+			return false
+		}
+		for _, verifier := range verifiers {
+			if _, err := os.Stat(filepath.Join(versionDir, verifier.Name)); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mirroredVersion returns whether a version was synced to the mirror in dir, which is when its sums file exists.
+func mirroredVersion(dir string, algorithm util.ChecksumAlgorithm) func(version string) bool {
+	return func(version string) bool {
+		_, err := os.Stat(filepath.Join(dir, version, algorithm.SumsFileName()))
+		return err == nil
+	}
+}
+
+func mirrorServe(dir string, globalOpts globalOpts, mirrorOpts mirrorOpts) error {
+	reporter := globalOpts.reporter()
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); errors.Is(err, os.ErrNotExist) {
+		return errors.New(dir + " is not a mirror, it has no index.json, run nvmc mirror sync " + dir + " first")
+	} else if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", mirrorOpts.listen)
+	if err != nil {
+		return err
+	}
+	url := "http://" + listener.Addr().String()
+	reporter.Message("serving " + dir + " at " + url + ", install from it with --download-url " + url)
+
+	server := &http.Server{Handler: mirrorHandler(dir), ReadHeaderTimeout: 30 * time.Second}
+	return server.Serve(listener)
+}
+
+// mirrorHandler serves the files of the mirror in dir, except the partial downloads of a sync, logging each request
+// to stderr.
+func mirrorHandler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(os.Stderr, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, ".part") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -1,30 +1,226 @@
 package cmd
 
+import (
+	"errors"
+	"github.com/spf13/pflag"
+	"nvmc/util"
+	"os"
+	"time"
+)
+
 type globalOpts struct {
-	downloadUrl     string
-	followRedirects bool
+	downloadUrl       string
+	followRedirects   bool
+	maxRedirects      int
+	connectTimeout    time.Duration
+	readTimeout       time.Duration
+	retries           int
+	caBundle          string
+	authToken         string
+	authUser          string
+	authPassword      string
+	output            string
+	verifySignature   string
+	keyring           string
+	checksumAlgorithm string
+	archiveFormat     string
+	offline           bool
+}
+
+var defaultGlobalOpts = globalOpts{"https://nodejs.org/dist", true, 10, 30 * time.Second, 60 * time.Second, 3, "", "", "", "", "text", "optional", "", "sha256", "tar.xz", false}
+
+func (o globalOpts) validate() error {
+	if o.output != "text" && o.output != "json" {
+		return errors.New("invalid output " + o.output + ", must be one of text, json")
+	}
+	if _, err := util.ParseSignaturePolicy(o.verifySignature); err != nil {
+		return err
+	}
+	if _, err := util.ParseChecksumAlgorithm(o.checksumAlgorithm); err != nil {
+		return err
+	}
+	if _, err := util.ParseArchiveFormat(o.archiveFormat); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reporter returns where progress and status messages are written. Text progress is written to stderr,
+// so that stdout only contains the status messages.
+func (o globalOpts) reporter() util.Reporter {
+	if o.output == "json" {
+		return util.NewJsonReporter(os.Stdout)
+	}
+	return util.NewTextReporter(os.Stdout, os.Stderr, 10*time.Second)
+}
+
+func (o globalOpts) downloadOptions() util.DownloadOptions {
+	return util.DownloadOptions{
+		FollowRedirects: o.followRedirects,
+		MaxRedirects:    o.maxRedirects,
+		ConnectTimeout:  o.connectTimeout,
+		ReadTimeout:     o.readTimeout,
+		Retries:         o.retries,
+		RetryDelay:      time.Second,
+		CABundle:        o.caBundle,
+		AuthToken:       o.authToken,
+		AuthUser:        o.authUser,
+		AuthPassword:    o.authPassword,
+		Offline:         o.offline,
+	}
+}
+
+// forTarget returns the options to download the builds of target. Builds that are only published to unofficial-builds
+// are downloaded from there, unless the download URL was changed, e.g. to a mirror that also has them.
+func (o globalOpts) forTarget(target util.Target) globalOpts {
+	if target.IsUnofficial() && o.downloadUrl == defaultGlobalOpts.downloadUrl {
+		o.downloadUrl = util.UnofficialBuildsUrl
+	}
+	return o
+}
+
+// targetOpts are the options of the commands that install or select a build for another platform, arch or C library.
+type targetOpts struct {
+	platform string
+	arch     string
+	libc     string
+}
+
+var defaultTargetOpts = targetOpts{"", "", ""}
+
+func (o targetOpts) target() (util.Target, error) {
+	return util.ParseTarget(o.platform, o.arch, o.libc)
 }
 
-var defaultGlobalOpts = globalOpts{"https://nodejs.org/dist", true}
+func addTargetFlags(flags *pflag.FlagSet, targetOpts *targetOpts) {
+	flags.StringVar(&targetOpts.platform, "platform", defaultTargetOpts.platform, "Platform of the build, one of linux, darwin, win, aix, sunos. Defaults to the current os.")
+	flags.StringVar(&targetOpts.arch, "arch", defaultTargetOpts.arch, "Arch of the build, e.g. x64, arm64, x86, armv7l. Defaults to the current arch.")
+	flags.StringVar(&targetOpts.libc, "libc", defaultTargetOpts.libc, "C library of the build, one of glibc, musl. musl builds are downloaded from unofficial-builds.nodejs.org.")
+}
+
+type bundleOpts struct {
+	out    string
+	target targetOpts
+}
+
+var defaultBundleOpts = bundleOpts{"nvmc-bundle.tar", defaultTargetOpts}
+
+type cacheOpts struct {
+	olderThan string
+}
+
+var defaultCacheOpts = cacheOpts{"30d"}
+
+type currentOpts struct {
+	source bool
+}
+
+var defaultCurrentOpts = currentOpts{false}
+
+type doctorOpts struct {
+	strict bool
+}
+
+var defaultDoctorOpts = doctorOpts{false}
+
+type envOpts struct {
+	shell   string
+	useOnCd bool
+}
+
+var defaultEnvOpts = envOpts{"", false}
+
+type execOpts struct {
+	install bool
+	target  targetOpts
+}
+
+var defaultExecOpts = execOpts{false, defaultTargetOpts}
+
+type infoOpts struct {
+}
+
+var defaultInfoOpts = infoOpts{}
 
 type installOpts struct {
 	skipChecksumValidation bool
 	use                    bool
+	aliases                []string
+	reinstall              bool
+	reinstallPackagesFrom  string
+	fromSource             bool
+	configureFlags         []string
+	jobs                   int
+	fromFile               string
+	checksum               string
+	sumsFile               string
+	target                 targetOpts
+	// skipAutoUse prevents activating the installed version when there is no current version.
+	skipAutoUse bool
 }
 
-var defaultInstallOpts = installOpts{false, false}
+var defaultInstallOpts = installOpts{false, false, []string{}, false, "", false, []string{}, 0, "", "", "", defaultTargetOpts, false}
+
+type linkOpts struct {
+	use bool
+}
+
+var defaultLinkOpts = linkOpts{false}
 
 type listOpts struct {
 }
 
 var defaultListOpts = listOpts{}
 
+type lsRemoteOpts struct {
+	lts bool
+}
+
+var defaultLsRemoteOpts = lsRemoteOpts{false}
+
+type mirrorOpts struct {
+	filter    string
+	platforms []string
+	listen    string
+}
+
+var defaultMirrorOpts = mirrorOpts{"", []string{}, "127.0.0.1:8080"}
+
+type repairOpts struct {
+	all                    bool
+	force                  bool
+	skipChecksumValidation bool
+}
+
+var defaultRepairOpts = repairOpts{false, false, false}
+
+type shellOpts struct {
+	shell string
+	auto  bool
+}
+
+var defaultShellOpts = shellOpts{"", false}
+
 type uninstallOpts struct {
 }
 
 var defaultUninstallOpts = uninstallOpts{}
 
+type upgradeOpts struct {
+	lts               bool
+	reinstallPackages bool
+}
+
+var defaultUpgradeOpts = upgradeOpts{false, true}
+
 type useOpts struct {
+	target targetOpts
+}
+
+var defaultUseOpts = useOpts{defaultTargetOpts}
+
+type verifyOpts struct {
+	all bool
 }
 
-var defaultUseOpts = useOpts{}
+var defaultVerifyOpts = verifyOpts{false}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type repairCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	repairOpts repairOpts
+}
+
+func newRepairCmd(globalOpts *globalOpts) *repairCmd {
+	cmd := &repairCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "repair [version]",
+		Short: "Reinstall a damaged version.",
+		Long: `Reinstall a damaged version, keeping its global npm packages.
+
+When <version> is omitted, the active version is repaired. <version> can also be an alias given with nvmc install --alias.
+A version is only reinstalled when nvmc verify finds it damaged, or when it can not be verified, unless --force is given.
+
+The version is downloaded and extracted next to the damaged installation, or built again when it was built from
+source, and the global npm packages that the archive does not contain are copied into it. The damaged installation
+is only replaced once that succeeded.
+Packages that the archive contains, e.g. npm, are restored to the version of the archive.`,
+		Example: `$ nvmc repair 18.2.0
+
+# Repair every damaged version.
+$ nvmc repair --all`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.repairOpts.all, "all", defaultRepairOpts.all, "Repair every damaged installed version.")
+	cmd.command.Flags().BoolVar(&cmd.repairOpts.force, "force", defaultRepairOpts.force, "Reinstall even when the version is not damaged.")
+	cmd.command.Flags().BoolVar(&cmd.repairOpts.skipChecksumValidation, "skip-checksum-validation", defaultRepairOpts.skipChecksumValidation, "Skip checksum validation after downloading.")
+
+	return cmd
+}
+
+func (c *repairCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		versions, err := selectInstalledVersions(args, c.repairOpts.all, *c.globalOpts)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			if err := repair(version, *c.globalOpts, c.repairOpts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// repair reinstalls the installed version, for the platform, arch and libc it was installed for.
+func repair(version string, globalOpts globalOpts, repairOpts repairOpts) error {
+	reporter := globalOpts.reporter()
+	lock, err := lockVersion(version, reporter)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	previous, err := util.ReadInstallMetadata(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return errors.New("version " + version + " is not installed")
+	} else if err != nil {
+		return err
+	}
+	if previous.IsLinked() && repairOpts.all {
+		reporter.Message(version + " is linked to " + previous.LinkedPath + ", skipping it")
+		return nil
+	} else if previous.IsLinked() {
+		return errors.New("version " + version + " is linked to " + previous.LinkedPath + ", rebuild it there instead")
+	}
+	if !repairOpts.force {
+		damaged, err := util.VerifyInstall(version)
+		if err != nil && !errors.Is(err, util.ErrNoManifest) {
+			return err
+		} else if err == nil && len(damaged) == 0 {
+			reporter.Message(version + " is intact, nothing to repair")
+			return nil
+		}
+	}
+
+	installationInfo, err := util.GetInstallationInfo(previous.Version, previous.Target())
+	if err != nil {
+		return err
+	}
+	tempDir, err := stageVersion(version)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	var metadata *util.InstallMetadata
+	if previous.FromSource {
+		metadata, err = stageSourceInstall(previous.Version, installationInfo, tempDir, repairOpts.skipChecksumValidation, previous.ConfigureFlags, 0, previous.Aliases, globalOpts, reporter)
+	} else {
+		metadata, err = stageInstall(previous.Version, installationInfo, tempDir, repairOpts.skipChecksumValidation, previous.Aliases, globalOpts.forTarget(previous.Target()), reporter)
+	}
+	if err != nil {
+		return err
+	}
+	if err := replaceInstall(version, previous, tempDir, metadata, reporter); err != nil {
+		return err
+	}
+
+	reporter.Message("successfully repaired " + version)
+	return nil
+}
+
+// replaceInstall replaces the installation of version, described by previous, with the one extracted to tempDir,
+// copying the global npm packages that the new installation does not have.
+func replaceInstall(version string, previous *util.InstallMetadata, tempDir string, metadata *util.InstallMetadata, reporter util.Reporter) error {
+	versionDir, err := util.GetVersionPath(version)
+	if err != nil {
+		return err
+	}
+	packages, err := util.CopyGlobalPackages(filepath.Join(versionDir, previous.Root), filepath.Join(tempDir, metadata.Root))
+	if err != nil {
+		return errors.New("unable to keep the global npm packages of " + version + ", " + err.Error())
+	}
+
+	if err := replaceVersion(version, versionDir, tempDir); err != nil {
+		return err
+	}
+
+	if len(packages) > 0 {
+		reporter.Message("kept the global npm packages " + strings.Join(packages, ", "))
+	}
+	return nil
+}
+
+// replaceVersion replaces the installation of version in versionDir with the one extracted to tempDir. The
+// installation is moved aside first and restored when the replacement can not be moved into place. It is only
+// missing if nvmc is killed in between, then the next lockVersion restores it, see util.RestorePreviousVersion.
+func replaceVersion(version string, versionDir string, tempDir string) error {
+	previousDir, err := util.GetPreviousVersionPath(version)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(versionDir, previousDir); err != nil {
+		return err
+	}
+	if err := moveStagedVersion(tempDir, versionDir); err != nil {
+		if restoreErr := os.Rename(previousDir, versionDir); restoreErr != nil {
+			return errors.New(err.Error() + ", unable to restore " + versionDir + " from " + previousDir + ": " + restoreErr.Error())
+		}
+		return err
+	}
+	return os.RemoveAll(previousDir)
+}
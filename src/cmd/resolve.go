@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"nvmc/util"
+	"os"
+	"slices"
+)
+
+// resolveRemoteVersion resolves a version expression against the versions available from the download URL.
+// Exact versions are returned as is, without fetching the index. For another target than the current os and arch,
+// only the versions that publish an archive for target are considered.
+func resolveRemoteVersion(expression string, target util.Target, globalOpts globalOpts) (string, error) {
+	if util.IsExactVersion(expression) {
+		return util.NormalizeVersion(expression)
+	}
+
+	entries, err := util.FetchIndex(globalOpts.forTarget(target).downloadUrl, globalOpts.downloadOptions())
+	if err != nil {
+		return "", err
+	}
+	if !target.IsNative() {
+		entries = slices.DeleteFunc(entries, func(entry util.IndexEntry) bool {
+			return !entry.HasArchive(target)
+		})
+	}
+
+	version, err := util.ResolveVersion(expression, entries)
+	if err != nil && globalOpts.offline {
+		return "", errors.New(err.Error() + ", only the versions imported with nvmc bundle import are available offline")
+	}
+	return version, err
+}
+
+// resolveInstalledVersion resolves a version expression, or an alias given at install, against the versions installed
+// for target, returning the name of the installed version, see util.InstallName. Exact versions are returned as is.
+// The lts keywords use the remote index to find which installed versions are LTS, in offline mode the index of the
+// last time it was downloaded.
+func resolveInstalledVersion(expression string, target util.Target, globalOpts globalOpts) (string, error) {
+	if util.IsExactVersion(expression) {
+		version, err := util.NormalizeVersion(expression)
+		if err != nil {
+			return "", err
+		}
+		return util.InstallName(version, target), nil
+	}
+
+	installed, _, err := util.ListInstallMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	entries := make([]util.IndexEntry, 0, len(installed))
+	for _, metadata := range installed {
+		if metadata.Name() == expression || slices.Contains(metadata.Aliases, expression) {
+			return metadata.Name(), nil
+		}
+		// Linked versions are only used by their name, not to resolve a version.
+		if metadata.Target() == target && !metadata.IsLinked() {
+			entries = append(entries, util.IndexEntry{Version: metadata.Version})
+		}
+	}
+
+	if util.IsLtsAlias(expression) {
+		remoteEntries, err := util.FetchIndex(globalOpts.downloadUrl, globalOpts.downloadOptions())
+		if errors.Is(err, util.ErrOffline) {
+			remoteEntries, err = util.ReadCachedIndex(globalOpts.downloadUrl)
+		}
+		if err != nil {
+			return "", err
+		}
+		ltsByVersion := make(map[string]util.Lts, len(remoteEntries))
+		for _, remoteEntry := range remoteEntries {
+			ltsByVersion[remoteEntry.Version] = remoteEntry.Lts
+		}
+		for i := range entries {
+			entries[i].Lts = ltsByVersion[entries[i].Version]
+		}
+	}
+
+	version, err := util.ResolveVersion(expression, entries)
+	if err != nil {
+		return "", err
+	}
+	return util.InstallName(version, target), nil
+}
+
+// versionExpression returns the version expression from args, or from the nearest version file
+// to the working directory when args is empty.
+func versionExpression(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	versionFile, err := util.FindVersionFile(workingDir)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintln(os.Stderr, "found "+describeVersionFile(versionFile)+" with version "+versionFile.Expression)
+
+	return versionFile.Expression, nil
+}
+
+func describeVersionFile(versionFile *util.VersionFile) string {
+	if len(versionFile.Field) > 0 {
+		return versionFile.Path + " (" + versionFile.Field + ")"
+	}
+	return versionFile.Path
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"github.com/spf13/cobra"
 	"nvmc/util"
 	"os"
+	"strconv"
 )
 
 type rootCmd struct {
@@ -17,10 +19,35 @@ func newRootCmd() *rootCmd {
 		Use:     "nvmc",
 		Short:   "Install and manage multiple versions of node",
 		Version: util.VERSION,
+		PersistentPreRunE: func(command *cobra.Command, args []string) error {
+			err := applyConfig(command.Root().PersistentFlags())
+			if err == nil {
+				err = cmd.globalOpts.validate()
+			}
+			// The config commands run with an invalid config, so that it can be fixed with them.
+			if err != nil && command.HasParent() && command.Parent().Name() == "config" {
+				return nil
+			}
+			return err
+		},
 	}
 
-	cmd.command.Flags().StringVar(&cmd.globalOpts.downloadUrl, "download-url", defaultGlobalOpts.downloadUrl, "Specify a custom base URL.")
-	cmd.command.Flags().BoolVar(&cmd.globalOpts.followRedirects, "follow-redirects", defaultGlobalOpts.followRedirects, "Follow redirects when downloading files.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.downloadUrl, "download-url", defaultGlobalOpts.downloadUrl, "Specify a custom base URL.")
+	cmd.command.PersistentFlags().BoolVar(&cmd.globalOpts.followRedirects, "follow-redirects", defaultGlobalOpts.followRedirects, "Follow redirects when downloading files.")
+	cmd.command.PersistentFlags().IntVar(&cmd.globalOpts.maxRedirects, "max-redirects", defaultGlobalOpts.maxRedirects, "Maximum number of redirects to follow when downloading files.")
+	cmd.command.PersistentFlags().DurationVar(&cmd.globalOpts.connectTimeout, "connect-timeout", defaultGlobalOpts.connectTimeout, "Timeout for connecting to the download URL, 0 to disable.")
+	cmd.command.PersistentFlags().DurationVar(&cmd.globalOpts.readTimeout, "read-timeout", defaultGlobalOpts.readTimeout, "Timeout for receiving data from the download URL, 0 to disable.")
+	cmd.command.PersistentFlags().IntVar(&cmd.globalOpts.retries, "retries", defaultGlobalOpts.retries, "Number of times to retry a download after a transient failure.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.caBundle, "ca-bundle", defaultGlobalOpts.caBundle, "PEM file with additional certificates to trust when downloading files.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.authToken, "auth-token", defaultGlobalOpts.authToken, "Bearer token sent when downloading files, prefer NVMC_AUTH_TOKEN or the config file.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.authUser, "auth-user", defaultGlobalOpts.authUser, "Basic authentication user sent when downloading files.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.authPassword, "auth-password", defaultGlobalOpts.authPassword, "Basic authentication password sent when downloading files, prefer NVMC_AUTH_PASSWORD or the config file.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.output, "output", defaultGlobalOpts.output, "Format of progress and status messages, one of text, json. json writes one JSON object per line to stdout.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.verifySignature, "verify-signature", defaultGlobalOpts.verifySignature, "Verify the signature of SHASUMS256.txt, one of required, optional, off. optional only verifies when the mirror publishes a signature.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.keyring, "keyring", defaultGlobalOpts.keyring, "Keyring with additional public keys trusted to sign SHASUMS256.txt, e.g. for an internal mirror.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.checksumAlgorithm, "checksum-algorithm", defaultGlobalOpts.checksumAlgorithm, "Algorithm of the sums file used to verify downloads, one of sha256 (SHASUMS256.txt), sha512 (SHASUMS512.txt) for mirrors that publish it.")
+	cmd.command.PersistentFlags().StringVar(&cmd.globalOpts.archiveFormat, "archive-format", defaultGlobalOpts.archiveFormat, "Archive format to favor when a version publishes several, one of tar.xz, tar.gz. Windows versions are always zip.")
+	cmd.command.PersistentFlags().BoolVar(&cmd.globalOpts.offline, "offline", defaultGlobalOpts.offline, "Do not access the network, only use the versions imported with nvmc bundle import. Fails instead of downloading.")
 
 	return cmd
 }
@@ -31,13 +58,51 @@ func Execute() {
 	rootCmd := newRootCmd()
 	// Hide the completions command, but keep it available
 	rootCmd.command.CompletionOptions.HiddenDefaultCmd = true
-	rootCmd.command.AddCommand(newInstallCmd(rootCmd.globalOpts).command)
-	rootCmd.command.AddCommand(newListCmd(rootCmd.globalOpts).command)
-	rootCmd.command.AddCommand(newUninstallCmd(rootCmd.globalOpts).command)
-	rootCmd.command.AddCommand(newUseCmd(rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newBundleCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newCacheCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newConfigCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newCurrentCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newDoctorCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newEnvCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newExecCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newInfoCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newInstallCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newLinkCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newListCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newLsRemoteCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newMirrorCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newRepairCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newRunCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newShellCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newUninstallCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newUpgradeCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newUseCmd(&rootCmd.globalOpts).command)
+	rootCmd.command.AddCommand(newVerifyCmd(&rootCmd.globalOpts).command)
 
 	err := rootCmd.command.Execute()
-	if err != nil {
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	} else if err != nil {
 		os.Exit(1)
 	}
 }
+
+// exitCodeError exits nvmc with code, used to pass through the exit code of a child process, or to fail without
+// printing an error after the command reported the problem itself.
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return "exit code " + strconv.Itoa(e.code)
+}
+
+// silenceExitCodeError prevents cobra from printing an exitCodeError, the problem was already reported.
+func silenceExitCodeError(cmd *cobra.Command, err error) {
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+}
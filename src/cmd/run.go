@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type runCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	execOpts   execOpts
+}
+
+func newRunCmd(globalOpts *globalOpts) *runCmd {
+	cmd := &runCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "run <version> [args...]",
+		Short: "Run node <version> with [args...], without changing the current version.",
+		Long: `Run node <version> with [args...], without changing the current version.
+
+Same as: nvmc exec <version> -- node [args...]. Use -- before [args...] when they contain flags for node.`,
+		Example: `# Run app.js with version 18.2.0.
+$ nvmc run 18.2.0 app.js
+
+# Pass flags to node.
+$ nvmc run 18.2.0 -- --inspect app.js`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.execOpts.install, "install", defaultExecOpts.install, "Install <version> when it is not installed.")
+
+	return cmd
+}
+
+func (c *runCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := execVersion(args[:1], append([]string{"node"}, args[1:]...), *c.globalOpts, c.execOpts)
+		silenceExitCodeError(cmd, err)
+		return err
+	}
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+)
+
+type shellCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	shellOpts  shellOpts
+}
+
+func newShellCmd(globalOpts *globalOpts) *shellCmd {
+	cmd := &shellCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "shell [version]",
+		Short: "Set <version> as the node version for the current shell only.",
+		Long: `Set <version> as the node version for the current shell only.
+
+Prints shell code that prepends the bin directory of <version> to PATH, the global symlink is left unchanged.
+The code must be evaluated by the shell, the wrapper installed by nvmc env does this automatically.
+When <version> is omitted, it is read from the nearest .nvmrc, .node-version, or package.json.`,
+		Example: `# Use version 18.2.0 in the current bash or zsh shell.
+$ eval "$(nvmc shell 18.2.0)"
+
+# Use version 18.2.0 in the current fish shell.
+$ nvmc shell 18.2.0 --shell fish | source
+
+# Use version 18.2.0 in the current PowerShell session.
+$ nvmc shell 18.2.0 --shell powershell | Out-String | Invoke-Expression`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().StringVar(&cmd.shellOpts.shell, "shell", defaultShellOpts.shell, "Shell to print code for, one of bash, zsh, fish, powershell. Detected when not set.")
+	cmd.command.Flags().BoolVar(&cmd.shellOpts.auto, "auto", defaultShellOpts.auto, "Only print code when the version file for the working directory requests a different version. Used by the nvmc env --use-on-cd hook.")
+
+	return cmd
+}
+
+func (c *shellCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return shell(args, *c.globalOpts, c.shellOpts)
+	}
+}
+
+func shell(args []string, globalOpts globalOpts, shellOpts shellOpts) error {
+	targetShell, err := shellFromOpts(shellOpts.shell)
+	if err != nil {
+		return err
+	}
+
+	var activation *util.ShellActivation
+	if shellOpts.auto {
+		activation, err = autoShellActivation(globalOpts)
+	} else {
+		activation, err = explicitShellActivation(args, globalOpts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if activation != nil {
+		fmt.Print(util.ShellScript(targetShell, *activation))
+	}
+
+	return nil
+}
+
+func shellFromOpts(shell string) (util.Shell, error) {
+	if len(shell) == 0 {
+		return util.DetectShell()
+	}
+	return util.ParseShell(shell)
+}
+
+func explicitShellActivation(args []string, globalOpts globalOpts) (*util.ShellActivation, error) {
+	expression, err := versionExpression(args)
+	if err != nil {
+		return nil, err
+	}
+	version, err := resolveInstalledVersion(expression, util.NativeTarget(), globalOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return shellActivation(version, "")
+}
+
+// autoShellActivation switches to the version requested by the nearest version file. When there is no
+// version file and the shell was activated from one, the shell falls back to the global symlink.
+// Problems are reported as warnings so that changing directories never fails.
+func autoShellActivation(globalOpts globalOpts) (*util.ShellActivation, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	versionFile, err := util.FindVersionFile(workingDir)
+	if errors.Is(err, util.ErrVersionFileNotFound) {
+		if len(os.Getenv(util.ShellSourceEnv)) == 0 {
+			return nil, nil
+		}
+		return &util.ShellActivation{Path: util.ShellPath(os.Getenv("PATH"), "", os.Getenv(util.ShellPathEnv))}, nil
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "nvmc: "+err.Error())
+		return nil, nil
+	}
+
+	// The hook runs on every prompt, so it never waits for the network, e.g. lts/* uses the cached index.
+	globalOpts.offline = true
+	version, err := resolveInstalledVersion(versionFile.Expression, util.NativeTarget(), globalOpts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nvmc: "+describeVersionFile(versionFile)+" requests "+versionFile.Expression+": "+err.Error())
+		return nil, nil
+	}
+	if version == os.Getenv(util.ShellVersionEnv) {
+		return nil, nil
+	}
+
+	activation, err := shellActivation(version, versionFile.Path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nvmc: "+err.Error())
+		return nil, nil
+	}
+	fmt.Fprintln(os.Stderr, "now using node "+version+" in this shell")
+
+	return activation, nil
+}
+
+func shellActivation(version string, source string) (*util.ShellActivation, error) {
+	binPath, err := util.GetBinPath(version)
+	if err == nil {
+		_, err = os.Stat(binPath)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("version " + version + " is not installed, run nvmc install " + version)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &util.ShellActivation{
+		Path:    util.ShellPath(os.Getenv("PATH"), binPath, os.Getenv(util.ShellPathEnv)),
+		BinPath: binPath,
+		Version: version,
+		Source:  source,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"nvmc/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stageSourceInstall downloads the source of version, builds it with ./configure and make, and installs it into
+// tempDir in the same layout as the prebuilt archive, with the install.json that records where it came from.
+// The source is extracted and built next to tempDir, and removed once it is installed.
+func stageSourceInstall(version string, installationInfo *util.InstallationInfo, tempDir string, skipChecksumValidation bool, configureFlags []string, jobs int, aliases []string, globalOpts globalOpts, reporter util.Reporter) (*util.InstallMetadata, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("unable to build " + version + " from source, building on windows is not supported")
+	}
+	if !installationInfo.Target().IsNative() {
+		return nil, errors.New("unable to build " + version + " from source for " + installationInfo.Target().String() + ", only the current platform and arch can be built")
+	}
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	preferred, err := util.ParseArchiveFormat(globalOpts.archiveFormat)
+	if err != nil {
+		return nil, err
+	}
+	formats := util.ArchiveFormats(preferred, installationInfo.Target())
+	// The source archive is named after the version only, e.g. node-v18.2.0.tar.gz.
+	sourceInfo := &util.InstallationInfo{FileNameWithoutExtension: "node-" + version}
+
+	var verifier *util.ChecksumVerifier
+	if !skipChecksumValidation {
+		verifier, err = fetchChecksumVerifier(version, sourceInfo, formats, globalOpts, reporter)
+		if errors.Is(err, util.ErrChecksumNotFound) {
+			return nil, errors.New("refusing to build " + version + " without verifying its source, " + err.Error() + ", use --skip-checksum-validation to build it anyway")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	sourceDir, err := os.MkdirTemp(filepath.Dir(tempDir), "source-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(sourceDir)
+
+	manifest, fileName, err := downloadAndUnzip(version, sourceInfo, formats, verifier, sourceDir, globalOpts, reporter)
+	if errors.Is(err, util.ErrNotFound) {
+		return nil, errors.New("the source of " + version + " was not found on the mirror " + globalOpts.downloadUrl + ", " + err.Error())
+	} else if err != nil {
+		return nil, err
+	}
+
+	// node and npm find their files relative to the node executable, so the installation keeps working when it is
+	// moved out of the staging directory.
+	root := filepath.Join(tempDir, installationInfo.FileNameWithoutExtension)
+	steps := [][]string{
+		append([]string{"./configure", "--prefix=" + root}, configureFlags...),
+		{"make", "-j" + strconv.Itoa(jobs)},
+		{"make", "install"},
+	}
+	for _, step := range steps {
+		reporter.Message("building " + version + " from source: " + strings.Join(step, " "))
+		if err := runBuildStep(manifest.Root, step); err != nil {
+			return nil, errors.New("unable to build " + version + " from source, " + strings.Join(step, " ") + " failed: " + err.Error())
+		}
+	}
+
+	files, err := util.ListFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := util.HashFiles(root, files)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &util.InstallMetadata{
+		Version:        version,
+		Root:           installationInfo.FileNameWithoutExtension,
+		Platform:       installationInfo.Platform,
+		Arch:           installationInfo.Arch,
+		Mirror:         globalOpts.downloadUrl,
+		SourceUrl:      globalOpts.downloadUrl + "/" + version + "/" + fileName,
+		InstalledAt:    time.Now().UTC(),
+		NvmcVersion:    util.VERSION,
+		Aliases:        aliases,
+		Files:          files,
+		FileHashes:     hashes,
+		FromSource:     true,
+		ConfigureFlags: configureFlags,
+	}
+	metadata.ArchiveFormat = util.ArchiveFormat(strings.TrimPrefix(fileName, sourceInfo.FileNameWithoutExtension))
+	if verifier != nil {
+		metadata.Checksum = string(verifier.Algorithm) + ":" + verifier.Expected
+	}
+	if err := util.WriteInstallMetadata(tempDir, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// runBuildStep runs a step of the build in dir. The output of the build is written to stderr, stdout only has the
+// status messages of nvmc.
+func runBuildStep(dir string, step []string) error {
+	child := exec.Command(step[0], step[1:]...)
+	child.Dir = dir
+	child.Stdout = os.Stderr
+	child.Stderr = os.Stderr
+	return child.Run()
+}
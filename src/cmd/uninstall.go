@@ -9,15 +9,19 @@ import (
 
 type uninstallCmd struct {
 	command       *cobra.Command
-	globalOpts    globalOpts
+	globalOpts    *globalOpts
 	uninstallOpts uninstallOpts
 }
 
-func newUninstallCmd(globalOpts globalOpts) *uninstallCmd {
+func newUninstallCmd(globalOpts *globalOpts) *uninstallCmd {
 	cmd := &uninstallCmd{}
 	cmd.command = &cobra.Command{
 		Use:   "uninstall <version>",
 		Short: "Uninstall <version>.",
+		Long: `Uninstall <version>.
+
+<version> can be an exact version, a partial version, a semver range, or one of latest, lts, lts/* and lts/<codename>.
+Anything other than an exact version is resolved to the newest matching installed version.`,
 		Example: `# Uninstall version 18.2.0.
 $ nvmc uninstall 18.2.0`,
 		Args: cobra.ExactArgs(1),
@@ -31,21 +35,25 @@ $ nvmc uninstall 18.2.0`,
 
 func (c *uninstallCmd) run() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		version := args[0]
-		return uninstall(version, c.globalOpts, c.uninstallOpts)
+		version, err := resolveInstalledVersion(args[0], util.NativeTarget(), *c.globalOpts)
+		if err != nil {
+			return err
+		}
+		return uninstall(version, *c.globalOpts, c.uninstallOpts)
 	}
 }
 
 func uninstall(version string, globalOpts globalOpts, uninstallOpts uninstallOpts) error {
-	version, err := util.NormalizeVersion(version)
+	currentVersionDir, err := util.GetVersionPath(version)
 	if err != nil {
 		return err
 	}
 
-	currentVersionDir, err := util.GetVersionPath(version)
+	lock, err := lockVersion(version, globalOpts.reporter())
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
 	stats, err := os.Stat(currentVersionDir)
 	if err != nil && errors.Is(err, os.ErrNotExist) {
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"strconv"
+)
+
+type upgradeCmd struct {
+	command     *cobra.Command
+	globalOpts  *globalOpts
+	upgradeOpts upgradeOpts
+}
+
+func newUpgradeCmd(globalOpts *globalOpts) *upgradeCmd {
+	cmd := &upgradeCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "upgrade [major]",
+		Short: "Install and use the newest version in the line of the active version.",
+		Long: `Install and use the newest version in the line of the active version, e.g. 18.20.4 when 18.2.0 is active.
+
+With <major>, the newest version of that major version is installed instead, and with --lts the newest LTS version.
+The global npm packages of the active version are reinstalled into the new version, unless --reinstall-packages=false
+is given. The active version is kept installed, uninstall it with nvmc uninstall <version>.`,
+		Example: `# Upgrade from 18.2.0 to the newest 18.x version.
+$ nvmc upgrade
+
+# Upgrade to the newest 20.x version.
+$ nvmc upgrade 20
+
+# Upgrade to the newest LTS version.
+$ nvmc upgrade --lts`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.upgradeOpts.lts, "lts", defaultUpgradeOpts.lts, "Upgrade to the newest LTS version.")
+	cmd.command.Flags().BoolVar(&cmd.upgradeOpts.reinstallPackages, "reinstall-packages", defaultUpgradeOpts.reinstallPackages, "Reinstall the global npm packages of the active version into the new version.")
+
+	return cmd
+}
+
+func (c *upgradeCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return upgrade(args, *c.globalOpts, c.upgradeOpts)
+	}
+}
+
+func upgrade(args []string, globalOpts globalOpts, upgradeOpts upgradeOpts) error {
+	reporter := globalOpts.reporter()
+	from, err := activeVersion()
+	if err != nil {
+		return errors.New("unable to upgrade without an active version, " + err.Error())
+	}
+	fromMetadata, err := util.ReadInstallMetadata(from)
+	if err != nil {
+		return err
+	}
+	fromVersion, err := semver.NewVersion(fromMetadata.Version)
+	if err != nil {
+		return err
+	}
+	// The new version is installed for the same platform, arch and libc as the active version.
+	target := fromMetadata.Target()
+
+	var expression string
+	switch {
+	case upgradeOpts.lts && len(args) > 0:
+		return errors.New("--lts can not be used with a major version")
+	case upgradeOpts.lts:
+		expression = "lts/*"
+	case len(args) > 0:
+		if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+			return errors.New("invalid major version " + args[0] + ", must be a number, e.g. 20")
+		}
+		expression = args[0]
+	default:
+		expression = strconv.FormatUint(fromVersion.Major(), 10)
+	}
+
+	version, err := resolveRemoteVersion(expression, target, globalOpts)
+	if err != nil {
+		return err
+	}
+	if newVersion, err := semver.NewVersion(version); err != nil {
+		return err
+	} else if !newVersion.GreaterThan(fromVersion) {
+		reporter.Message(from + " is already the newest version, the newest available is " + version)
+		return nil
+	}
+
+	name := util.InstallName(version, target)
+	versionDir, err := util.GetVersionPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(versionDir); errors.Is(err, os.ErrNotExist) {
+		installOpts := defaultInstallOpts
+		installOpts.use = true
+		installOpts.target = targetOpts{target.Platform, target.Arch, target.Libc}
+		if upgradeOpts.reinstallPackages {
+			installOpts.reinstallPackagesFrom = from
+		}
+		return install(version, globalOpts, installOpts)
+	} else if err != nil {
+		return err
+	}
+
+	reporter.Message(name + " is already installed")
+	if err := use(name, reporter); err != nil {
+		return err
+	}
+	if upgradeOpts.reinstallPackages {
+		return reinstallGlobalPackages(from, name, reporter)
+	}
+	return nil
+}
@@ -2,49 +2,70 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
 	"github.com/spf13/cobra"
 	"nvmc/util"
 	"os"
-	"path/filepath"
-	"runtime"
 )
 
 type useCmd struct {
 	command    *cobra.Command
-	globalOpts globalOpts
+	globalOpts *globalOpts
 	useOpts    useOpts
 }
 
-func newUseCmd(globalOpts globalOpts) *useCmd {
+func newUseCmd(globalOpts *globalOpts) *useCmd {
 	cmd := &useCmd{}
 	cmd.command = &cobra.Command{
-		Use:   "use <version>",
+		Use:   "use [version]",
 		Short: "Set <version> to the current node version.",
+		Long: `Set <version> to the current node version.
+
+When <version> is omitted, it is read from the nearest .nvmrc, .node-version, or package.json (volta.node or
+engines.node) in the working directory or one of its parents.
+
+<version> can be an exact version, a partial version, a semver range, or one of latest, lts, lts/* and lts/<codename>.
+Anything other than an exact version is resolved to the newest matching installed version.
+With --platform, --arch or --libc, only the versions installed with the same options are used.`,
 		Example: `# Use version 18.2.0.
-$ nvmc use 18.2.0`,
-		Args: cobra.ExactArgs(1),
+$ nvmc use 18.2.0
+
+# Use the x64 build of 18.2.0, installed with nvmc install 18.2.0 --arch x64.
+$ nvmc use 18.2.0 --arch x64
+
+# Use the newest installed 18.x version.
+$ nvmc use 18
+
+# Use the version from .nvmrc.
+$ nvmc use`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: cmd.run(),
 	}
 
 	cmd.globalOpts = globalOpts
+	addTargetFlags(cmd.command.Flags(), &cmd.useOpts.target)
 
 	return cmd
 }
 
 func (c *useCmd) run() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		version := args[0]
-		return use(version)
+		expression, err := versionExpression(args)
+		if err != nil {
+			return err
+		}
+		target, err := c.useOpts.target.target()
+		if err != nil {
+			return err
+		}
+		version, err := resolveInstalledVersion(expression, target, *c.globalOpts)
+		if err != nil {
+			return err
+		}
+		return use(version, c.globalOpts.reporter())
 	}
 }
 
-func use(version string) error {
-	version, err := util.NormalizeVersion(version)
-	if err != nil {
-		return err
-	}
-
+func use(version string, reporter util.Reporter) error {
 	// TODO: Validate the version
 	currentVersionDir, err := util.GetVersionPath(version)
 	if err != nil {
@@ -64,26 +85,39 @@ func use(version string) error {
 		return err
 	}
 
-	if err := os.Remove(nodeSymLink); err != nil && !errors.Is(err, os.ErrNotExist) {
+	symLinkTarget, err := util.GetBinPath(version)
+	if err != nil {
 		return err
 	}
-
-	installationInfo, err := util.GetInstallationInfo(version)
+	nodePath, err := util.GetNodePath(version)
 	if err != nil {
 		return err
 	}
+	if _, err := os.Stat(nodePath); err != nil {
+		if metadata, err := util.ReadInstallMetadata(version); err == nil && metadata.IsLinked() {
+			return errors.New("version " + version + " is broken, " + nodePath + " is missing, rebuild " + metadata.LinkedPath + " or remove the link with nvmc uninstall " + version)
+		}
+		return errors.New("version " + version + " is damaged, " + nodePath + " is missing, run nvmc repair " + version + " to reinstall it")
+	}
 
-	var symLinkTarget string
-	if runtime.GOOS == "windows" {
-		symLinkTarget = filepath.Join(currentVersionDir, installationInfo.FileNameWithoutExtension)
-	} else {
-		symLinkTarget = filepath.Join(currentVersionDir, installationInfo.FileNameWithoutExtension, "bin")
+	lock, err := lockSymLink(reporter)
+	if err != nil {
+		return err
 	}
-	if err := os.Symlink(symLinkTarget, nodeSymLink); err != nil {
+	defer lock.Unlock()
+
+	if err := util.ReplaceSymlink(symLinkTarget, nodeSymLink); err != nil {
 		return err
 	}
 
-	fmt.Println("now using node " + version)
+	reporter.Message("now using node " + version)
 
 	return nil
 }
+
+// lockSymLink returns the lock that is held while the symlink of the active version is changed.
+func lockSymLink(reporter util.Reporter) (*util.FileLock, error) {
+	return util.LockFile("symlink", func() {
+		reporter.Message("waiting for another nvmc process to change the active version")
+	})
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"nvmc/util"
+	"os"
+	"strconv"
+)
+
+type verifyCmd struct {
+	command    *cobra.Command
+	globalOpts *globalOpts
+	verifyOpts verifyOpts
+}
+
+func newVerifyCmd(globalOpts *globalOpts) *verifyCmd {
+	cmd := &verifyCmd{}
+	cmd.command = &cobra.Command{
+		Use:   "verify [version]",
+		Short: "Check that an installed version has not been damaged.",
+		Long: `Check that the files of an installed version are the same as when it was installed.
+
+When <version> is omitted, the active version is verified. <version> can also be an alias given with nvmc install --alias.
+Files added since it was installed, e.g. global npm packages, are not checked.
+
+Versions installed by an older nvmc did not record their files, and can not be verified. nvmc repair reinstalls them
+with a record of their files.
+
+Exits with code 1 when a version is damaged. With --output json, each version is printed as a JSON line.`,
+		Example: `$ nvmc verify 18.2.0
+
+# Verify every installed version.
+$ nvmc verify --all`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.run(),
+	}
+
+	cmd.globalOpts = globalOpts
+	cmd.command.Flags().BoolVar(&cmd.verifyOpts.all, "all", defaultVerifyOpts.all, "Verify every installed version.")
+
+	return cmd
+}
+
+func (c *verifyCmd) run() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		versions, err := selectInstalledVersions(args, c.verifyOpts.all, *c.globalOpts)
+		if err != nil {
+			return err
+		}
+		err = verify(versions, *c.globalOpts, c.verifyOpts)
+		silenceExitCodeError(cmd, err)
+		return err
+	}
+}
+
+// verifyResult is the result of verifying an installed version, as printed with --output json.
+type verifyResult struct {
+	Version string             `json:"version"`
+	Status  string             `json:"status"`
+	Files   []util.DamagedFile `json:"files"`
+}
+
+func verify(versions []string, globalOpts globalOpts, verifyOpts verifyOpts) error {
+	failed := false
+	for _, version := range versions {
+		result := verifyResult{version, "ok", []util.DamagedFile{}}
+		damaged, err := util.VerifyInstall(version)
+		if errors.Is(err, util.ErrNoManifest) {
+			result.Status = "unverifiable"
+		} else if errors.Is(err, util.ErrLinkedVersion) {
+			result.Status = "linked"
+		} else if err != nil {
+			return err
+		} else if len(damaged) > 0 {
+			result.Status, result.Files = "damaged", damaged
+			failed = true
+		}
+
+		if globalOpts.output == "json" {
+			contents, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(contents))
+			continue
+		}
+		switch result.Status {
+		case "ok":
+			fmt.Println(version + " is intact")
+		case "unverifiable":
+			fmt.Println(version + " can not be verified, it was installed by an older nvmc that did not record its files")
+			fmt.Println("    fix: nvmc repair " + version + " reinstalls it with a record of its files")
+		case "linked":
+			fmt.Println(version + " is linked to an external build, nvmc does not verify it")
+		case "damaged":
+			fmt.Println(version + " is damaged, " + strconv.Itoa(len(damaged)) + " files changed since it was installed")
+			for _, file := range damaged {
+				fmt.Println("    " + file.Reason + " " + file.Path)
+			}
+			fmt.Println("    fix: nvmc repair " + version)
+		}
+	}
+
+	if failed {
+		return &exitCodeError{1}
+	}
+	return nil
+}
+
+// selectInstalledVersions returns every installed version when all is set, otherwise the installed version that
+// matches the version in args, or the active version when args is empty.
+func selectInstalledVersions(args []string, all bool, globalOpts globalOpts) ([]string, error) {
+	if all {
+		if len(args) > 0 {
+			return nil, errors.New("--all can not be used with a version")
+		}
+		installed, _, err := util.ListInstallMetadata()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		versions := make([]string, 0, len(installed))
+		for _, metadata := range installed {
+			versions = append(versions, metadata.Name())
+		}
+		return versions, nil
+	}
+
+	var version string
+	var err error
+	if len(args) == 0 {
+		version, err = activeVersion()
+	} else {
+		version, err = resolveInstalledVersion(args[0], util.NativeTarget(), globalOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{version}, nil
+}
@@ -6,7 +6,16 @@ require github.com/Masterminds/semver/v3 v3.2.1
 
 require github.com/spf13/cobra v1.8.1
 
+require github.com/spf13/pflag v1.0.5
+
+require (
+	github.com/ProtonMail/go-crypto v1.1.3
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/sys v0.16.0
+)
+
 require (
+	github.com/cloudflare/circl v1.3.7 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
 )
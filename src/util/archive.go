@@ -0,0 +1,94 @@
+package util
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ArchiveFormat is the file extension of a node archive.
+type ArchiveFormat string
+
+const (
+	ArchiveTarXz ArchiveFormat = ".tar.xz"
+	ArchiveTarGz ArchiveFormat = ".tar.gz"
+	ArchiveZip   ArchiveFormat = ".zip"
+)
+
+// ParseArchiveFormat parses the preferred archive format, one of tar.xz, tar.gz or zip.
+func ParseArchiveFormat(format string) (ArchiveFormat, error) {
+	switch ArchiveFormat("." + strings.TrimPrefix(strings.ToLower(format), ".")) {
+	case ArchiveTarXz:
+		return ArchiveTarXz, nil
+	case ArchiveTarGz:
+		return ArchiveTarGz, nil
+	case ArchiveZip:
+		return ArchiveZip, nil
+	default:
+		return "", errors.New("invalid archive format " + format + ", must be one of tar.xz, tar.gz, zip")
+	}
+}
+
+// ArchiveFormats returns the archive formats published for the platform of target, starting with preferred.
+// Windows versions are only published as zip, other platforms as tar.xz and tar.gz. Old versions only have tar.gz.
+func ArchiveFormats(preferred ArchiveFormat, target Target) []ArchiveFormat {
+	if target.Platform == "win" {
+		return []ArchiveFormat{ArchiveZip}
+	}
+	if preferred == ArchiveTarGz {
+		return []ArchiveFormat{ArchiveTarGz, ArchiveTarXz}
+	}
+	return []ArchiveFormat{ArchiveTarXz, ArchiveTarGz}
+}
+
+// SelectArchive returns a verifier for the first of formats that sums lists for the archive
+// fileNameWithoutExtension, failing with a ChecksumNotFoundError when sums lists none of them.
+func SelectArchive(sums map[string]string, fileNameWithoutExtension string, formats []ArchiveFormat, algorithm ChecksumAlgorithm) (*ChecksumVerifier, error) {
+	for _, format := range formats {
+		if verifier, err := NewChecksumVerifier(sums, fileNameWithoutExtension+string(format), algorithm); err == nil {
+			return verifier, nil
+		}
+	}
+	return NewChecksumVerifier(sums, fileNameWithoutExtension+string(formats[0]), algorithm)
+}
+
+// HasArchive returns whether the files list of the entry has an archive for target.
+// Entries without a files list, e.g. from a mirror that does not publish them, are assumed to have one.
+func (e IndexEntry) HasArchive(target Target) bool {
+	return len(e.Files) == 0 || slices.Contains(e.Files, target.indexFile())
+}
+
+// ParseArchiveName returns the version, target and format of the name of a node archive,
+// e.g. node-v18.2.0-linux-x64-musl.tar.xz.
+func ParseArchiveName(name string) (string, Target, ArchiveFormat, error) {
+	invalid := errors.New("unable to parse the archive name " + name + ", expected a name like node-v18.2.0-linux-x64.tar.xz")
+	var format ArchiveFormat
+	for _, f := range []ArchiveFormat{ArchiveTarXz, ArchiveTarGz, ArchiveZip} {
+		if strings.HasSuffix(name, string(f)) {
+			format = f
+		}
+	}
+	if len(format) == 0 || !strings.HasPrefix(name, "node-") {
+		return "", Target{}, "", invalid
+	}
+
+	// The version can have a prerelease, e.g. node-v20.0.0-rc.1-linux-x64, so the target is parsed from the end.
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(name, "node-"), string(format)), "-")
+	libc := ""
+	if parts[len(parts)-1] == LibcMusl {
+		libc, parts = LibcMusl, parts[:len(parts)-1]
+	}
+	if len(parts) < 3 {
+		return "", Target{}, "", invalid
+	}
+	version := strings.Join(parts[:len(parts)-2], "-")
+	if !IsExactVersion(version) {
+		return "", Target{}, "", invalid
+	}
+	target, err := ParseTarget(parts[len(parts)-2], parts[len(parts)-1], libc)
+	if err != nil {
+		return "", Target{}, "", errors.New("unable to parse the archive name " + name + ", " + err.Error())
+	}
+	version, err = NormalizeVersion(version)
+	return version, target, format, err
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseArchiveFormat(t *testing.T) {
+	for format, expectFormat := range map[string]ArchiveFormat{"tar.xz": ArchiveTarXz, ".tar.gz": ArchiveTarGz, "ZIP": ArchiveZip} {
+		actual, err := ParseArchiveFormat(format)
+		if err != nil || actual != expectFormat {
+			t.Fatalf(`ParseArchiveFormat(%q) = %q, %v, Wanted = %q`, format, actual, err, expectFormat)
+		}
+	}
+	if _, err := ParseArchiveFormat("7z"); err == nil {
+		t.Fatalf(`ParseArchiveFormat(%q) error = nil, Wanted an error`, "7z")
+	}
+}
+
+func TestArchiveFormatsStartWithPreferred(t *testing.T) {
+	formats := ArchiveFormats(ArchiveTarGz, Target{"linux", "x64", ""})
+	if !slices.Equal(formats, []ArchiveFormat{ArchiveTarGz, ArchiveTarXz}) {
+		t.Fatalf(`ArchiveFormats(%q) = %q, Wanted = %q`, ArchiveTarGz, formats, []ArchiveFormat{ArchiveTarGz, ArchiveTarXz})
+	}
+	formats = ArchiveFormats(ArchiveTarGz, Target{"win", "x64", ""})
+	if !slices.Equal(formats, []ArchiveFormat{ArchiveZip}) {
+		t.Fatalf(`ArchiveFormats(%q) = %q, Wanted = %q`, ArchiveTarGz, formats, []ArchiveFormat{ArchiveZip})
+	}
+}
+
+func TestSelectArchiveFallsBackToPublishedFormat(t *testing.T) {
+	sums := map[string]string{"node-v0.12.18-linux-x64.tar.gz": checksumTestSha256}
+	verifier, err := SelectArchive(sums, "node-v0.12.18-linux-x64", []ArchiveFormat{ArchiveTarXz, ArchiveTarGz}, ChecksumSha256)
+	if err != nil || verifier.Name != "node-v0.12.18-linux-x64.tar.gz" {
+		t.Fatalf(`SelectArchive() = %v, %v, Wanted = %q`, verifier, err, "node-v0.12.18-linux-x64.tar.gz")
+	}
+}
+
+func TestSelectArchiveErrorWhenNoFormatIsPublished(t *testing.T) {
+	sums := map[string]string{"node-v18.2.0-darwin-x64.tar.gz": checksumTestSha256}
+	if _, err := SelectArchive(sums, "node-v18.2.0-linux-x64", []ArchiveFormat{ArchiveTarXz, ArchiveTarGz}, ChecksumSha256); !errors.Is(err, ErrChecksumNotFound) {
+		t.Fatalf(`SelectArchive() error = %v, Wanted = %v`, err, ErrChecksumNotFound)
+	}
+}
+
+func TestIndexEntryHasArchive(t *testing.T) {
+	target := NativeTarget()
+	if !(IndexEntry{Version: "v18.2.0"}).HasArchive(target) {
+		t.Fatalf(`HasArchive() without files = false, Wanted = true`)
+	}
+	if !(IndexEntry{Version: "v18.2.0", Files: []string{"headers", target.indexFile()}}).HasArchive(target) {
+		t.Fatalf(`HasArchive() with %q = false, Wanted = true`, target.indexFile())
+	}
+	if (IndexEntry{Version: "v18.2.0", Files: []string{"headers", "src"}}).HasArchive(target) {
+		t.Fatalf(`HasArchive() without %q = true, Wanted = false`, target.indexFile())
+	}
+	musl := Target{"linux", "x64", LibcMusl}
+	if !(IndexEntry{Version: "v18.2.0", Files: []string{"linux-x64", "linux-x64-musl"}}).HasArchive(musl) {
+		t.Fatalf(`HasArchive() with %q = false, Wanted = true`, "linux-x64-musl")
+	}
+}
+
+func TestParseArchiveName(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		target  Target
+		format  ArchiveFormat
+	}{
+		{"node-v18.2.0-linux-x64.tar.xz", "v18.2.0", Target{"linux", "x64", ""}, ArchiveTarXz},
+		{"node-v18.2.0-linux-arm64-musl.tar.gz", "v18.2.0", Target{"linux", "arm64", LibcMusl}, ArchiveTarGz},
+		{"node-v20.0.0-rc.1-win-x64.zip", "v20.0.0-rc.1", Target{"win", "x64", ""}, ArchiveZip},
+	}
+	for _, test := range tests {
+		version, target, format, err := ParseArchiveName(test.name)
+		if err != nil || version != test.version || target != test.target || format != test.format {
+			t.Fatalf(`ParseArchiveName(%q) = %q, %v, %q, %v, Wanted = %q, %v, %q`, test.name, version, target, format, err, test.version, test.target, test.format)
+		}
+	}
+}
+
+func TestParseArchiveNameInvalid(t *testing.T) {
+	for _, name := range []string{"node-v18.2.0.tar.gz", "node-v18.2.0-linux-x64.7z", "nodejs-v18.2.0-linux-x64.tar.gz", "node-18-linux-x64.tar.gz", "node-v18.2.0-plan9-x64.tar.gz"} {
+		if version, _, _, err := ParseArchiveName(name); err == nil {
+			t.Fatalf(`ParseArchiveName(%q) = %q, Wanted an error`, name, version)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package util
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// BundleWriter writes a bundle, a tar file with the files of one or more mirrors for hosts without internet access.
+// Files are named by OfflineFileName. index.json is imported into the offline store, archives into the cache, and
+// every other file, e.g. SHASUMS256.txt and its signature, into the offline store. A sums file must be written before
+// the archives it lists.
+type BundleWriter struct {
+	tar *tar.Writer
+}
+
+func NewBundleWriter(writer io.Writer) *BundleWriter {
+	return &BundleWriter{tar.NewWriter(writer)}
+}
+
+// Add writes contents as the file named name.
+func (b *BundleWriter) Add(name string, contents []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := b.tar.Write(contents)
+	return err
+}
+
+// AddFile writes the file at filePath as the file named name.
+func (b *BundleWriter) AddFile(name string, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := b.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(b.tar, file)
+	return err
+}
+
+func (b *BundleWriter) Close() error {
+	return b.tar.Close()
+}
+
+// ImportBundle imports the files of the bundle read from reader, see BundleWriter. Archives are verified against the
+// sums file next to them before they are stored in the cache. Returns the names of the imported archives.
+func ImportBundle(reader io.Reader) ([]string, error) {
+	archives := make([]string, 0)
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return archives, errors.New("unable to read the bundle: " + err.Error())
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(header.Name)) {
+			return archives, errors.New("invalid file " + header.Name + " in the bundle")
+		}
+
+		name := path.Base(header.Name)
+		if _, _, _, err := ParseArchiveName(name); err == nil {
+			verifier, err := offlineVerifier(header.Name)
+			if err != nil {
+				return archives, err
+			}
+			if _, err := CacheFile(tarReader, verifier); err != nil {
+				return archives, err
+			}
+			archives = append(archives, name)
+			continue
+		}
+
+		contents, err := io.ReadAll(tarReader)
+		if err != nil {
+			return archives, errors.New("unable to read the bundle: " + err.Error())
+		}
+		if name == "index.json" {
+			entries, err := ParseIndex(contents)
+			if err != nil {
+				return archives, err
+			}
+			err = MergeOfflineIndex(header.Name, entries)
+		} else {
+			err = WriteOfflineFile(header.Name, contents)
+		}
+		if err != nil {
+			return archives, err
+		}
+	}
+	return archives, nil
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func writeTestBundle(t *testing.T, files [][2]string) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	writer := NewBundleWriter(buf)
+	for _, file := range files {
+		if err := writer.Add(file[0], []byte(file[1])); err != nil {
+			t.Fatalf(`Add(%q) error = %v`, file[0], err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf(`Close() error = %v`, err)
+	}
+	return buf
+}
+
+func TestImportBundle(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := "node-v18.2.0-linux-x64.tar.gz"
+	bundle := writeTestBundle(t, [][2]string{
+		{"nodejs.org/dist/index.json", `[{"version":"v18.2.0","lts":false}]`},
+		{"nodejs.org/dist/v18.2.0/SHASUMS256.txt", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  " + archive + "\n"},
+		{"nodejs.org/dist/v18.2.0/" + archive, ""},
+	})
+
+	archives, err := ImportBundle(bundle)
+	if err != nil || len(archives) != 1 || archives[0] != archive {
+		t.Fatalf(`ImportBundle() = %v, %v, Wanted = [%s]`, archives, err, archive)
+	}
+	entryPath, _ := GetCacheEntryPath(archive, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	if _, err := os.Stat(entryPath); err != nil {
+		t.Fatalf(`ImportBundle() did not cache %s: %v`, archive, err)
+	}
+	opts := testDownloadOptions()
+	opts.Offline = true
+	if entries, err := FetchIndex("https://nodejs.org/dist", opts); err != nil || len(entries) != 1 {
+		t.Fatalf(`FetchIndex() = %v, %v, Wanted the imported index`, entries, err)
+	}
+}
+
+func TestImportBundleInvalid(t *testing.T) {
+	archive := "node-v18.2.0-linux-x64.tar.gz"
+	tests := map[string][][2]string{
+		"checksum mismatch": {
+			{"nodejs.org/dist/v18.2.0/SHASUMS256.txt", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  " + archive + "\n"},
+			{"nodejs.org/dist/v18.2.0/" + archive, "modified"},
+		},
+		"missing sums":  {{"nodejs.org/dist/v18.2.0/" + archive, ""}},
+		"outside store": {{"../index.json", "[]"}},
+	}
+	for name, files := range tests {
+		t.Setenv("NVMC_HOME", t.TempDir())
+		if _, err := ImportBundle(writeTestBundle(t, files)); err == nil {
+			t.Fatalf(`ImportBundle() of a bundle with %s error = nil, Wanted an error`, name)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheEntry is a downloaded archive in the cache. Complete archives are stored at
+// <cache>/<checksum>/<name>, downloads that have not finished at <cache>/partial/<checksum>-<name>.part.
+type CacheEntry struct {
+	Path     string
+	Name     string
+	Checksum string
+	Size     int64
+	// LastUsed is updated every time the entry is used by an install.
+	LastUsed time.Time
+	Partial  bool
+}
+
+func GetCachePath() (string, error) {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "cache"), nil
+}
+
+func GetCacheEntryPath(name string, checksum string) (string, error) {
+	cacheDir, err := GetCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, strings.ToLower(checksum), name), nil
+}
+
+func getCachePartialPath(name string, checksum string) (string, error) {
+	cacheDir, err := GetCachePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "partial", strings.ToLower(checksum)+"-"+name+".part"), nil
+}
+
+// CacheDownload returns the path of the cached file verified by verifier, downloading it from url when it is
+// not cached. The file is hashed while it is downloaded. An interrupted download is resumed the next time the
+// same file is requested.
+func CacheDownload(url string, verifier *ChecksumVerifier, opts DownloadOptions) (string, error) {
+	entryPath, cached, err := cachedEntry(verifier)
+	if err != nil || cached {
+		return entryPath, err
+	}
+	return downloadToCache(url, verifier, verifier, opts)
+}
+
+// IsCached returns whether the file verified by verifier is in the cache, without verifying it.
+func IsCached(verifier *ChecksumVerifier) bool {
+	entryPath, err := GetCacheEntryPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(entryPath)
+	return err == nil
+}
+
+// CacheDownloadAndUnzip extracts the archive verified by verifier into basePath, downloading it from url into the
+// cache when it is not cached. tar archives are extracted while they are downloaded and hashed, zip archives once
+// they are downloaded, as are tar archives when the server does not resume a partial download. The extracted files must
+// be discarded when an error is returned, including a checksum mismatch.
+func CacheDownloadAndUnzip(url string, verifier *ChecksumVerifier, basePath string, opts DownloadOptions) (*Manifest, error) {
+	entryPath, cached, err := cachedEntry(verifier)
+	if err != nil {
+		return nil, err
+	}
+	if !cached && !strings.HasSuffix(verifier.Name, string(ArchiveZip)) {
+		manifest, err := streamToCache(url, verifier, basePath, opts)
+		if !errors.Is(err, errRestartedDownload) {
+			return manifest, err
+		}
+		// The server sent the whole archive instead of the rest of the partial download, which was already
+		// extracted. Discard the extracted files and extract the archive once it is downloaded instead.
+		if err := os.RemoveAll(basePath); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(basePath, fs.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	if !cached {
+		if entryPath, err = downloadToCache(url, verifier, verifier, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	archive, err := os.Open(entryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	return Unzip(archive, basePath, opts.Reporter)
+}
+
+// streamToCache downloads the tar archive verified by verifier into the cache, extracting it into basePath from
+// the downloaded bytes through a pipe.
+func streamToCache(url string, verifier *ChecksumVerifier, basePath string, opts DownloadOptions) (*Manifest, error) {
+	reader, writer := io.Pipe()
+	downloaded := make(chan error, 1)
+	go func() {
+		_, err := downloadToCache(url, verifier, io.MultiWriter(verifier, writer), opts)
+		writer.CloseWithError(err)
+		downloaded <- err
+	}()
+
+	manifest, err := UnzipStream(reader, verifier.Name, basePath)
+	if err == nil {
+		// Read the end of the archive after the last entry, so that the download completes.
+		_, err = io.Copy(io.Discard, reader)
+	}
+	// Stop the download when the extraction failed.
+	reader.CloseWithError(err)
+
+	// A failed download also fails the extraction with the same error, and the other way around.
+	downloadErr := <-downloaded
+	if errors.Is(downloadErr, errRestartedDownload) {
+		return nil, downloadErr
+	} else if err != nil {
+		return nil, err
+	} else if downloadErr != nil {
+		return nil, downloadErr
+	}
+	return manifest, nil
+}
+
+// cachedEntry returns the path of the cache entry verified by verifier, and whether it is cached.
+// A cache entry that does not match its checksum is removed.
+func cachedEntry(verifier *ChecksumVerifier) (string, bool, error) {
+	entryPath, err := GetCacheEntryPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return "", false, err
+	}
+
+	if _, err := os.Stat(entryPath); err == nil {
+		if verifier.VerifyFile(entryPath) == nil {
+			now := time.Now()
+			if err := os.Chtimes(entryPath, now, now); err != nil {
+				return "", false, err
+			}
+			return entryPath, true, nil
+		}
+		// The cached file was modified, replace it.
+		if err := os.Remove(entryPath); err != nil {
+			return "", false, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", false, err
+	}
+
+	return entryPath, false, nil
+}
+
+// downloadToCache downloads the file verified by verifier into the cache, writing its contents to tee, which must
+// write to verifier, while they are downloaded.
+func downloadToCache(url string, verifier *ChecksumVerifier, tee io.Writer, opts DownloadOptions) (string, error) {
+	entryPath, err := GetCacheEntryPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return "", err
+	}
+	partialPath, err := getCachePartialPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(partialPath), fs.ModePerm); err != nil {
+		return "", err
+	}
+
+	verifier.Reset()
+	if err := DownloadFile(url, partialPath, tee, opts); errors.Is(err, errRestartedDownload) {
+		// Remove the discarded partial download, so that the next download does not resume it.
+		_ = os.Remove(partialPath)
+		return "", err
+	} else if err != nil {
+		return "", err
+	}
+
+	if err := verifier.Verify(); err != nil {
+		if removeErr := os.Remove(partialPath); removeErr != nil {
+			return "", removeErr
+		}
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entryPath), fs.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.Rename(partialPath, entryPath); err != nil {
+		return "", err
+	}
+
+	return entryPath, nil
+}
+
+// CacheFile stores the file read from reader in the cache as the file verified by verifier, e.g. an archive from a
+// bundle, returning the path of the cache entry.
+func CacheFile(reader io.Reader, verifier *ChecksumVerifier) (string, error) {
+	entryPath, err := GetCacheEntryPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return "", err
+	}
+	partialPath, err := getCachePartialPath(verifier.Name, verifier.Expected)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(partialPath), fs.ModePerm); err != nil {
+		return "", err
+	}
+
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return "", err
+	}
+	verifier.Reset()
+	_, err = io.Copy(io.MultiWriter(file, verifier), reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verifier.Verify()
+	}
+	if err != nil {
+		_ = os.Remove(partialPath)
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entryPath), fs.ModePerm); err != nil {
+		return "", err
+	}
+	return entryPath, os.Rename(partialPath, entryPath)
+}
+
+// ListCache returns every complete and partial entry in the cache.
+func ListCache() ([]CacheEntry, error) {
+	entries := make([]CacheEntry, 0)
+	cacheDir, err := GetCachePath()
+	if err != nil {
+		return entries, err
+	}
+
+	err = filepath.WalkDir(cacheDir, func(path string, dirEntry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == cacheDir {
+			return fs.SkipDir
+		} else if err != nil {
+			return err
+		}
+		if dirEntry.IsDir() {
+			return nil
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		entry := CacheEntry{Path: path, Size: info.Size(), LastUsed: info.ModTime()}
+		parent := filepath.Base(filepath.Dir(path))
+		if parent == "partial" {
+			checksum, name, _ := strings.Cut(strings.TrimSuffix(dirEntry.Name(), ".part"), "-")
+			entry.Name, entry.Checksum, entry.Partial = name, checksum, true
+		} else {
+			entry.Name, entry.Checksum = dirEntry.Name(), parent
+		}
+		entries = append(entries, entry)
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// PruneCache removes the entries that were last used before olderThan ago, returning the removed entries.
+func PruneCache(olderThan time.Duration) ([]CacheEntry, error) {
+	removed := make([]CacheEntry, 0)
+	entries, err := ListCache()
+	if err != nil {
+		return removed, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	for _, entry := range entries {
+		if !entry.LastUsed.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return removed, err
+		}
+		if !entry.Partial {
+			// Remove the checksum directory, ignoring the error when it still has other files.
+			_ = os.Remove(filepath.Dir(entry.Path))
+		}
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// CleanCache removes the whole cache.
+func CleanCache() error {
+	cacheDir, err := GetCachePath()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(cacheDir)
+}
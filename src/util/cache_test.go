@@ -0,0 +1,243 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var cacheTestContents = bytes.Repeat([]byte("node archive contents "), 1024)
+
+func cacheTestChecksum() string {
+	sum := sha256.Sum256(cacheTestContents)
+	return hex.EncodeToString(sum[:])
+}
+
+func cacheTestVerifier(t *testing.T, checksum string) *ChecksumVerifier {
+	verifier, err := NewChecksumVerifier(map[string]string{"node.tar.gz": checksum}, "node.tar.gz", ChecksumSha256)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return verifier
+}
+
+func newCacheTestServer(ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "node.tar.gz", time.Time{}, bytes.NewReader(cacheTestContents))
+	}))
+}
+
+func TestCacheDownloadReusesCachedFile(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	ranges := make([]string, 0)
+	server := newCacheTestServer(&ranges)
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		path, err := CacheDownload(server.URL, cacheTestVerifier(t, cacheTestChecksum()), testDownloadOptions())
+		if err != nil {
+			t.Fatalf(`CacheDownload() error = %v`, err)
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(contents, cacheTestContents) {
+			t.Fatalf(`CacheDownload() contents do not match, error = %v`, err)
+		}
+	}
+	if len(ranges) != 1 {
+		t.Fatalf(`CacheDownload() requests = %d, Wanted = %d`, len(ranges), 1)
+	}
+}
+
+func TestCacheDownloadResumesPartialFile(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	ranges := make([]string, 0)
+	server := newCacheTestServer(&ranges)
+	defer server.Close()
+
+	partialPath, err := getCachePartialPath("node.tar.gz", cacheTestChecksum())
+	if err != nil {
+		t.Fatalf("Failed to get partial path: %v", err)
+	}
+	writeTestFile(t, partialPath, string(cacheTestContents[:1000]))
+
+	path, err := CacheDownload(server.URL, cacheTestVerifier(t, cacheTestChecksum()), testDownloadOptions())
+	if err != nil {
+		t.Fatalf(`CacheDownload() error = %v`, err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(contents, cacheTestContents) {
+		t.Fatalf(`CacheDownload() contents do not match, error = %v`, err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=1000-" {
+		t.Fatalf(`CacheDownload() ranges = %q, Wanted = %q`, ranges, []string{"bytes=1000-"})
+	}
+}
+
+func TestCacheDownloadErrorOnChecksumMismatch(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	ranges := make([]string, 0)
+	server := newCacheTestServer(&ranges)
+	defer server.Close()
+
+	checksum := hex.EncodeToString(make([]byte, sha256.Size))
+	if _, err := CacheDownload(server.URL, cacheTestVerifier(t, checksum), testDownloadOptions()); err == nil {
+		t.Fatalf(`CacheDownload() error = nil, Wanted an error`)
+	}
+	if entries, err := ListCache(); err != nil || len(entries) != 0 {
+		t.Fatalf(`ListCache() = %v, %v, Wanted no entries`, entries, err)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	oldPath, _ := GetCacheEntryPath("old.tar.gz", "aaaa")
+	newPath, _ := GetCacheEntryPath("new.tar.gz", "bbbb")
+	writeTestFile(t, oldPath, "old")
+	writeTestFile(t, newPath, "new")
+	lastMonth := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(oldPath, lastMonth, lastMonth); err != nil {
+		t.Fatalf("Failed to change times: %v", err)
+	}
+
+	removed, err := PruneCache(7 * 24 * time.Hour)
+	if err != nil || len(removed) != 1 || removed[0].Name != "old.tar.gz" || removed[0].Checksum != "aaaa" {
+		t.Fatalf(`PruneCache() = %+v, %v, Wanted old.tar.gz removed`, removed, err)
+	}
+	if _, err := os.Stat(filepath.Dir(oldPath)); !os.IsNotExist(err) {
+		t.Fatalf(`PruneCache() left %s`, filepath.Dir(oldPath))
+	}
+	if _, err := os.Stat(newPath); err != nil {
+		t.Fatalf(`PruneCache() removed %s: %v`, newPath, err)
+	}
+}
+
+func newCacheTestArchiveServer(archive []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "node.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+}
+
+func cacheTestArchiveVerifier(t *testing.T, name string, archive []byte) *ChecksumVerifier {
+	sum := sha256.Sum256(archive)
+	verifier, err := NewChecksumVerifier(map[string]string{name: hex.EncodeToString(sum[:])}, name, ChecksumSha256)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return verifier
+}
+
+func TestCacheDownloadAndUnzipStreamsTar(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := newTestTarGz(t, testDir("node/"), testFile("node/bin/node", string(cacheTestContents)))
+	server := newCacheTestArchiveServer(archive)
+	defer server.Close()
+
+	// Resume from a partial download, which must be extracted as well.
+	verifier := cacheTestArchiveVerifier(t, "node.tar.gz", archive)
+	partialPath, err := getCachePartialPath("node.tar.gz", verifier.Expected)
+	if err != nil {
+		t.Fatalf("Failed to get partial path: %v", err)
+	}
+	writeTestFile(t, partialPath, string(archive[:100]))
+
+	basePath := t.TempDir()
+	manifest, err := CacheDownloadAndUnzip(server.URL, verifier, basePath, testDownloadOptions())
+	if err != nil {
+		t.Fatalf(`CacheDownloadAndUnzip() error = %v`, err)
+	}
+	contents, err := os.ReadFile(filepath.Join(manifest.Root, "bin", "node"))
+	if err != nil || !bytes.Equal(contents, cacheTestContents) {
+		t.Fatalf(`CacheDownloadAndUnzip() contents do not match, error = %v`, err)
+	}
+	entryPath, _ := GetCacheEntryPath("node.tar.gz", verifier.Expected)
+	if cached, err := os.ReadFile(entryPath); err != nil || !bytes.Equal(cached, archive) {
+		t.Fatalf(`CacheDownloadAndUnzip() did not cache the archive, error = %v`, err)
+	}
+}
+
+func TestCacheDownloadAndUnzipRestartsWhenRangeIsIgnored(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := newTestTarGz(t, testDir("node/"), testFile("node/bin/node", string(cacheTestContents)))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	verifier := cacheTestArchiveVerifier(t, "node.tar.gz", archive)
+	partialPath, err := getCachePartialPath("node.tar.gz", verifier.Expected)
+	if err != nil {
+		t.Fatalf("Failed to get partial path: %v", err)
+	}
+	writeTestFile(t, partialPath, string(archive[:100]))
+
+	basePath := t.TempDir()
+	manifest, err := CacheDownloadAndUnzip(server.URL, verifier, basePath, testDownloadOptions())
+	if err != nil {
+		t.Fatalf(`CacheDownloadAndUnzip() error = %v`, err)
+	}
+	contents, err := os.ReadFile(filepath.Join(manifest.Root, "bin", "node"))
+	if err != nil || !bytes.Equal(contents, cacheTestContents) {
+		t.Fatalf(`CacheDownloadAndUnzip() contents do not match, error = %v`, err)
+	}
+	if requests != 2 {
+		t.Fatalf(`CacheDownloadAndUnzip() requests = %d, Wanted = %d`, requests, 2)
+	}
+	if entries, err := ListCache(); err != nil || len(entries) != 1 || entries[0].Partial {
+		t.Fatalf(`ListCache() = %v, %v, Wanted the complete archive only`, entries, err)
+	}
+}
+
+func TestCacheDownloadAndUnzipReadsZipFromCache(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := newTestZip(t, testDir("node/"), testFile("node/node.exe", "node"))
+	server := newCacheTestArchiveServer(archive)
+	defer server.Close()
+
+	manifest, err := CacheDownloadAndUnzip(server.URL, cacheTestArchiveVerifier(t, "node.zip", archive), t.TempDir(), testDownloadOptions())
+	if err != nil {
+		t.Fatalf(`CacheDownloadAndUnzip() error = %v`, err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(manifest.Root, "node.exe")); err != nil || string(contents) != "node" {
+		t.Fatalf(`CacheDownloadAndUnzip() node.exe = %q, %v, Wanted = %q`, contents, err, "node")
+	}
+}
+
+func TestCacheDownloadAndUnzipErrorOnChecksumMismatch(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := newTestTarGz(t, testDir("node/"), testFile("node/bin/node", "node"))
+	server := newCacheTestArchiveServer(archive)
+	defer server.Close()
+
+	verifier := cacheTestArchiveVerifier(t, "node.tar.gz", []byte("another archive"))
+	if _, err := CacheDownloadAndUnzip(server.URL, verifier, t.TempDir(), testDownloadOptions()); err == nil {
+		t.Fatalf(`CacheDownloadAndUnzip() error = nil, Wanted an error`)
+	}
+	if entries, err := ListCache(); err != nil || len(entries) != 0 {
+		t.Fatalf(`ListCache() = %v, %v, Wanted no entries`, entries, err)
+	}
+}
+
+func TestCacheDownloadAndUnzipStopsDownloadOnUnsafeArchive(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	archive := newTestTarGz(t, testFile("../evil", "evil"), testFile("node/bin/node", string(cacheTestContents)))
+	server := newCacheTestArchiveServer(archive)
+	defer server.Close()
+
+	_, err := CacheDownloadAndUnzip(server.URL, cacheTestArchiveVerifier(t, "node.tar.gz", archive), t.TempDir(), testDownloadOptions())
+	if !errors.Is(err, ErrUnsafeArchive) {
+		t.Fatalf(`CacheDownloadAndUnzip() error = %v, Wanted = %v`, err, ErrUnsafeArchive)
+	}
+}
@@ -0,0 +1,137 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumNotFound matches a ChecksumNotFoundError.
+var ErrChecksumNotFound = errors.New("checksum not found")
+
+// ChecksumNotFoundError is returned when a sums file does not list the file being verified.
+type ChecksumNotFoundError struct {
+	SumsFileName string
+	Name         string
+}
+
+func (e *ChecksumNotFoundError) Error() string {
+	return e.SumsFileName + " does not list " + e.Name
+}
+
+func (e *ChecksumNotFoundError) Is(target error) bool {
+	return target == ErrChecksumNotFound
+}
+
+type ChecksumAlgorithm string
+
+const (
+	ChecksumSha256 ChecksumAlgorithm = "sha256"
+	ChecksumSha512 ChecksumAlgorithm = "sha512"
+)
+
+func ParseChecksumAlgorithm(algorithm string) (ChecksumAlgorithm, error) {
+	switch ChecksumAlgorithm(strings.ToLower(algorithm)) {
+	case ChecksumSha256:
+		return ChecksumSha256, nil
+	case ChecksumSha512:
+		return ChecksumSha512, nil
+	default:
+		return "", errors.New("invalid checksum algorithm " + algorithm + ", must be one of sha256, sha512")
+	}
+}
+
+// SumsFileName returns the name of the sums file published for the algorithm, e.g. SHASUMS256.txt.
+func (a ChecksumAlgorithm) SumsFileName() string {
+	if a == ChecksumSha512 {
+		return "SHASUMS512.txt"
+	}
+	return "SHASUMS256.txt"
+}
+
+func (a ChecksumAlgorithm) New() hash.Hash {
+	if a == ChecksumSha512 {
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+// ParseSums parses a sums file in the format written by sha256sum and sha512sum. Each line is a hex checksum
+// followed by two spaces and the file name, or a space and an asterisk for files hashed in binary mode.
+func ParseSums(contents []byte, algorithm ChecksumAlgorithm) (map[string]string, error) {
+	checksumLength := algorithm.New().Size() * 2
+	sums := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		checksum, name, found := strings.Cut(line, " ")
+		if !found || len(checksum) != checksumLength {
+			return nil, errors.New("unable to parse line " + strconv.Itoa(lineNumber) + " of " + algorithm.SumsFileName())
+		}
+		if _, err := hex.DecodeString(checksum); err != nil {
+			return nil, errors.New("unable to parse line " + strconv.Itoa(lineNumber) + " of " + algorithm.SumsFileName())
+		}
+		// The separator is a second space for text mode, or an asterisk for binary mode.
+		if !strings.HasPrefix(name, " ") && !strings.HasPrefix(name, "*") {
+			return nil, errors.New("unable to parse line " + strconv.Itoa(lineNumber) + " of " + algorithm.SumsFileName())
+		}
+		sums[name[1:]] = strings.ToLower(checksum)
+	}
+
+	return sums, scanner.Err()
+}
+
+// ChecksumVerifier hashes everything written to it, so that a file can be verified while it is downloaded.
+type ChecksumVerifier struct {
+	hash.Hash
+	Algorithm ChecksumAlgorithm
+	Name      string
+	Expected  string
+}
+
+// NewChecksumVerifier returns a verifier for name, failing with a ChecksumNotFoundError when sums does not list it.
+func NewChecksumVerifier(sums map[string]string, name string, algorithm ChecksumAlgorithm) (*ChecksumVerifier, error) {
+	expected, found := sums[name]
+	if !found {
+		return nil, &ChecksumNotFoundError{SumsFileName: algorithm.SumsFileName(), Name: name}
+	}
+	return &ChecksumVerifier{Hash: algorithm.New(), Algorithm: algorithm, Name: name, Expected: expected}, nil
+}
+
+// Verify compares the checksum of everything written since the last Reset with the expected checksum.
+func (v *ChecksumVerifier) Verify() error {
+	if actual := hex.EncodeToString(v.Sum(nil)); actual != v.Expected {
+		return errors.New("checksum of " + v.Name + " does not match, expected " + v.Expected + " but was " + actual)
+	}
+	return nil
+}
+
+// VerifyFile resets the verifier and verifies the file at path.
+func (v *ChecksumVerifier) VerifyFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	v.Reset()
+	if _, err := io.Copy(v, file); err != nil {
+		return err
+	}
+	return v.Verify()
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const checksumTestSha256 = "39a201466504ca912817459ac28725ae346cfa61a5639d3afedc61768a617cf6"
+
+func TestParseSums(t *testing.T) {
+	contents := checksumTestSha256 + "  node-v18.2.0-linux-x64.tar.gz\n" +
+		strings.ToUpper(checksumTestSha256) + " *node-v18.2.0-win-x64.zip\r\n" +
+		"\n"
+
+	sums, err := ParseSums([]byte(contents), ChecksumSha256)
+	if err != nil {
+		t.Fatalf(`ParseSums() error = %v`, err)
+	}
+	if sums["node-v18.2.0-linux-x64.tar.gz"] != checksumTestSha256 || sums["node-v18.2.0-win-x64.zip"] != checksumTestSha256 || len(sums) != 2 {
+		t.Fatalf(`ParseSums() = %v, Wanted both files with %s`, sums, checksumTestSha256)
+	}
+}
+
+func TestParseSumsErrorOnMalformedLine(t *testing.T) {
+	for _, contents := range []string{
+		"not a checksum  node-v18.2.0-linux-x64.tar.gz\n",
+		checksumTestSha256 + "node-v18.2.0-linux-x64.tar.gz\n",
+		checksumTestSha256 + " node-v18.2.0-linux-x64.tar.gz\n",
+		checksumTestSha256[:63] + "z  node-v18.2.0-linux-x64.tar.gz\n",
+	} {
+		if _, err := ParseSums([]byte(contents), ChecksumSha256); err == nil {
+			t.Fatalf(`ParseSums(%q) error = nil, Wanted an error`, contents)
+		}
+	}
+	if _, err := ParseSums([]byte(checksumTestSha256+"  node.tar.gz\n"), ChecksumSha512); err == nil {
+		t.Fatalf(`ParseSums() of a sha256 checksum as sha512 error = nil, Wanted an error`)
+	}
+}
+
+func TestNewChecksumVerifierFailsClosed(t *testing.T) {
+	sums := map[string]string{"node-v18.2.0-linux-x64.tar.gz": checksumTestSha256}
+	if _, err := NewChecksumVerifier(sums, "node-v18.2.0-linux-x64.tar.xz", ChecksumSha256); !errors.Is(err, ErrChecksumNotFound) {
+		t.Fatalf(`NewChecksumVerifier() error = %v, Wanted = %v`, err, ErrChecksumNotFound)
+	}
+}
+
+func TestChecksumVerifier(t *testing.T) {
+	sum := sha512.Sum512([]byte("node archive"))
+	sums := map[string]string{"node.tar.gz": hex.EncodeToString(sum[:])}
+
+	verifier, err := NewChecksumVerifier(sums, "node.tar.gz", ChecksumSha512)
+	if err != nil {
+		t.Fatalf(`NewChecksumVerifier() error = %v`, err)
+	}
+	_, _ = verifier.Write([]byte("node "))
+	_, _ = verifier.Write([]byte("archive"))
+	if err := verifier.Verify(); err != nil {
+		t.Fatalf(`Verify() error = %v`, err)
+	}
+
+	verifier.Reset()
+	_, _ = verifier.Write([]byte("tampered archive"))
+	if err := verifier.Verify(); err == nil {
+		t.Fatalf(`Verify() of tampered contents error = nil, Wanted an error`)
+	}
+}
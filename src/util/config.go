@@ -0,0 +1,62 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config is the persistent configuration stored as JSON in <NVMC_HOME>/config.json.
+// Keys are the names of the global flags, e.g. download-url.
+type Config map[string]string
+
+func GetConfigPath() (string, error) {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "config.json"), nil
+}
+
+// LoadConfig reads the config file, a missing config file is an empty Config.
+func LoadConfig() (Config, error) {
+	config := make(Config)
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return config, err
+	}
+
+	contents, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return config, errors.New("unable to parse config file " + configPath + ": " + err.Error())
+	}
+	return config, nil
+}
+
+// Save writes the config file, only readable by the current user as it can have credentials, e.g. auth-token.
+func (c Config) Save() error {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The temporary file of WriteFileAtomic is created with 0600.
+	return WriteFileAtomic(configPath, append(contents, '\n'))
+}
+
+// ConfigEnv returns the environment variable that overrides the config key, e.g. NVMC_DOWNLOAD_URL for download-url.
+func ConfigEnv(key string) string {
+	return "NVMC_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
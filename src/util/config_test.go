@@ -0,0 +1,55 @@
+package util
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestConfigSaveAndLoad(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	config, err := LoadConfig()
+	if err != nil || len(config) != 0 {
+		t.Fatalf(`LoadConfig() = %v, %v, Wanted an empty config`, config, err)
+	}
+
+	config["download-url"] = "https://mirror.example.com/node"
+	if err := config.Save(); err != nil {
+		t.Fatalf(`Config.Save() error = %v`, err)
+	}
+
+	config, err = LoadConfig()
+	if err != nil || config["download-url"] != "https://mirror.example.com/node" {
+		t.Fatalf(`LoadConfig() = %v, %v, Wanted download-url to be set`, config, err)
+	}
+
+	configPath, _ := GetConfigPath()
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatalf(`Config.Save() did not write %s: %v`, configPath, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf(`Config.Save() wrote %s with mode %v, Wanted = %v`, configPath, info.Mode().Perm(), fs.FileMode(0600))
+	}
+}
+
+func TestLoadConfigErrorOnInvalidJson(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("NVMC_HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "config.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf(`LoadConfig() error = nil, Wanted an error`)
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	if env := ConfigEnv("follow-redirects"); env != "NVMC_FOLLOW_REDIRECTS" {
+		t.Fatalf(`ConfigEnv("follow-redirects") = %q, Wanted = %q`, env, "NVMC_FOLLOW_REDIRECTS")
+	}
+}
@@ -0,0 +1,276 @@
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiagnosticLevel is the severity of a Diagnostic.
+type DiagnosticLevel string
+
+const (
+	DiagnosticOk      DiagnosticLevel = "ok"
+	DiagnosticWarning DiagnosticLevel = "warning"
+	DiagnosticError   DiagnosticLevel = "error"
+)
+
+// Diagnostic is the result of a single check of the environment, with the action that fixes a problem.
+type Diagnostic struct {
+	Level   DiagnosticLevel `json:"level"`
+	Check   string          `json:"check"`
+	Message string          `json:"message"`
+	Fix     string          `json:"fix,omitempty"`
+}
+
+// Diagnose checks NVMC_HOME, the installed versions, and whether the node of the active version is the first one
+// in the search path pathEnv, usually the PATH environment variable. shellBinPath is the bin path activated by
+// nvmc shell, or empty when it was not used.
+func Diagnose(pathEnv string, shellBinPath string) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	diagnostics = append(diagnostics, diagnoseHome()...)
+	diagnostics = append(diagnostics, diagnoseSymLink()...)
+	diagnostics = append(diagnostics, diagnosePath(pathEnv, shellBinPath)...)
+	diagnostics = append(diagnostics, diagnoseInstalls()...)
+	diagnostics = append(diagnostics, diagnoseLeftovers()...)
+	return diagnostics
+}
+
+func diagnoseHome() []Diagnostic {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return []Diagnostic{{DiagnosticError, "home", "unable to find NVMC_HOME: " + err.Error(), "set NVMC_HOME to the directory nvmc should install versions to"}}
+	}
+
+	info, err := os.Stat(nvmcHome)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Diagnostic{{DiagnosticWarning, "home", "NVMC_HOME " + nvmcHome + " does not exist, no version was installed yet", "install a version with nvmc install <version>"}}
+	} else if err != nil {
+		return []Diagnostic{{DiagnosticError, "home", "unable to read NVMC_HOME " + nvmcHome + ": " + err.Error(), "fix the permissions of " + nvmcHome}}
+	} else if !info.IsDir() {
+		return []Diagnostic{{DiagnosticError, "home", "NVMC_HOME " + nvmcHome + " is not a directory", "remove " + nvmcHome + ", or set NVMC_HOME to another directory"}}
+	}
+
+	diagnostics := make([]Diagnostic, 0)
+	for _, dir := range []string{nvmcHome, filepath.Join(nvmcHome, "versions"), filepath.Join(nvmcHome, "cache"), filepath.Join(nvmcHome, "locks"), filepath.Join(nvmcHome, "staging")} {
+		if err := checkWritable(dir); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, "home", dir + " is not writable: " + err.Error(), "fix the permissions of " + dir + ", it must be writable by the user that runs nvmc"})
+		}
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticOk, "home", "NVMC_HOME is " + nvmcHome, ""})
+	}
+	return diagnostics
+}
+
+// checkWritable returns an error when a file can not be created in dir. A dir that does not exist is created by
+// nvmc when it is needed, so it is not checked.
+func checkWritable(dir string) error {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	file, err := os.CreateTemp(dir, ".nvmc-doctor-*")
+	if err != nil {
+		return err
+	}
+	_ = file.Close()
+	return os.Remove(file.Name())
+}
+
+func diagnoseSymLink() []Diagnostic {
+	symLink, err := GetSymLinkPath()
+	if err != nil {
+		return []Diagnostic{{DiagnosticError, "symlink", err.Error(), ""}}
+	}
+
+	info, err := os.Lstat(symLink)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Diagnostic{{DiagnosticError, "symlink", "no version is active, " + symLink + " does not exist", "activate a version with nvmc use <version>"}}
+	} else if err != nil {
+		return []Diagnostic{{DiagnosticError, "symlink", "unable to read " + symLink + ": " + err.Error(), "fix the permissions of " + filepath.Dir(symLink)}}
+	} else if info.Mode()&fs.ModeSymlink == 0 {
+		return []Diagnostic{{DiagnosticError, "symlink", symLink + " is not a symlink", "remove " + symLink + " and activate a version with nvmc use <version>"}}
+	}
+
+	target, err := os.Readlink(symLink)
+	if err != nil {
+		return []Diagnostic{{DiagnosticError, "symlink", "unable to read " + symLink + ": " + err.Error(), ""}}
+	}
+	if _, err := os.Stat(symLink); err != nil {
+		return []Diagnostic{{DiagnosticError, "symlink", "the active version is missing, " + symLink + " points to " + target + ", which does not exist", "activate an installed version with nvmc use <version>"}}
+	}
+	return []Diagnostic{{DiagnosticOk, "symlink", symLink + " points to " + target, ""}}
+}
+
+func diagnosePath(pathEnv string, shellBinPath string) []Diagnostic {
+	symLink, err := GetSymLinkPath()
+	if err != nil {
+		return []Diagnostic{{DiagnosticError, "path", err.Error(), ""}}
+	}
+
+	entries := filepath.SplitList(pathEnv)
+	nvmcIndex := -1
+	for i, entry := range entries {
+		if isSamePath(entry, symLink) || (len(shellBinPath) > 0 && isSamePath(entry, shellBinPath)) {
+			nvmcIndex = i
+			break
+		}
+	}
+	if nvmcIndex < 0 {
+		fix := "add " + symLink + " to the start of PATH in your shell profile, e.g. export PATH=\"" + symLink + ":$PATH\""
+		if runtime.GOOS == "windows" {
+			fix = "add " + symLink + " to the start of the Path environment variable of your user"
+		}
+		return []Diagnostic{{DiagnosticError, "path", symLink + " is not in PATH, node of nvmc is not found by your shell", fix}}
+	}
+
+	diagnostics := make([]Diagnostic, 0)
+	// PATH often has a directory and a symlink to it, e.g. /bin and /usr/bin, report each executable once.
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		if i == nvmcIndex || len(entry) == 0 {
+			continue
+		}
+		for _, name := range nodeExecutables() {
+			file := filepath.Join(entry, name)
+			if info, err := os.Stat(file); err != nil || info.IsDir() {
+				continue
+			}
+			if realFile, err := filepath.EvalSymlinks(file); err == nil {
+				if seen[realFile] {
+					continue
+				}
+				seen[realFile] = true
+			}
+			if i < nvmcIndex {
+				diagnostics = append(diagnostics, Diagnostic{DiagnosticError, "path", file + " shadows the active version, " + entry + " is before " + entries[nvmcIndex] + " in PATH", "remove " + entry + " from PATH, or move " + entries[nvmcIndex] + " before it"})
+			} else {
+				diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "path", "another installation of node has " + file + ", it is not used while " + entries[nvmcIndex] + " is before it in PATH", "uninstall it to avoid confusion when PATH changes"})
+			}
+		}
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticOk, "path", entries[nvmcIndex] + " is the only directory in PATH with node", ""})
+	}
+	return diagnostics
+}
+
+func nodeExecutables() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"node.exe", "npm.cmd", "npx.cmd"}
+	}
+	return []string{"node", "npm", "npx"}
+}
+
+func isSamePath(a string, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func diagnoseInstalls() []Diagnostic {
+	installed, invalid, err := ListInstallMetadata()
+	if errors.Is(err, os.ErrNotExist) {
+		return []Diagnostic{}
+	} else if err != nil {
+		return []Diagnostic{{DiagnosticError, "installs", "unable to list the installed versions: " + err.Error(), ""}}
+	}
+
+	diagnostics := make([]Diagnostic, 0)
+	versionsDir, _ := GetVersionsPath()
+	for _, name := range invalid {
+		dir := filepath.Join(versionsDir, name)
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "installs", dir + " is not a valid version", "remove " + dir})
+	}
+	for _, metadata := range installed {
+		name := metadata.Name()
+		node, err := GetNodePath(name)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, "installs", "unable to find " + name + ": " + err.Error(), ""})
+			continue
+		}
+		repair := "repair it with nvmc repair " + name
+		if metadata.IsLinked() {
+			repair = "rebuild " + metadata.LinkedPath + ", or remove the link with nvmc uninstall " + name
+		}
+		if info, err := os.Stat(node); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, "installs", name + " is broken, " + node + " does not exist", repair})
+		} else if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, "installs", name + " is broken, " + node + " is not executable", "chmod +x " + node + ", or " + repair})
+		}
+	}
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticOk, "installs", strconv.Itoa(len(installed)) + " installed versions", ""})
+	}
+	return diagnostics
+}
+
+// leftoverTempFileAge is how long a temporary file of nvmc must be unchanged to be reported as left behind.
+const leftoverTempFileAge = time.Hour
+
+func diagnoseLeftovers() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return diagnostics
+	}
+	stagingDir := filepath.Join(nvmcHome, "staging")
+	stagingList, _ := os.ReadDir(stagingDir)
+	for _, dirEntry := range stagingList {
+		version := dirEntry.Name()
+		if locked, err := IsLocked(VersionLockName(version)); err != nil || locked {
+			// Another nvmc process is installing the version.
+			continue
+		}
+		dir := filepath.Join(stagingDir, version)
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "leftovers", dir + " was left behind by an install that did not finish", "remove " + dir + ", the next install of " + version + " also removes it"})
+	}
+
+	// A repair that did not finish leaves the version it replaces next to it, see GetPreviousVersionPath.
+	versionsDir := filepath.Join(nvmcHome, "versions")
+	versionsList, _ := os.ReadDir(versionsDir)
+	for _, dirEntry := range versionsList {
+		version, found := strings.CutSuffix(dirEntry.Name(), ".previous")
+		if !found || !strings.HasPrefix(version, ".") {
+			continue
+		}
+		version = strings.TrimPrefix(version, ".")
+		if locked, err := IsLocked(VersionLockName(version)); err != nil || locked {
+			// Another nvmc process is replacing the version.
+			continue
+		}
+		dir := filepath.Join(versionsDir, dirEntry.Name())
+		if _, err := os.Stat(filepath.Join(versionsDir, version)); errors.Is(err, os.ErrNotExist) {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "leftovers", version + " was moved to " + dir + " by a repair that did not finish", "restore it with nvmc repair " + version})
+		} else {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "leftovers", dir + " was left behind by a repair that did not finish", "remove " + dir + ", the next install or repair of " + version + " also removes it"})
+		}
+	}
+
+	// Archives that are installed without a checksum are downloaded to the temporary directory. The files do not
+	// have a lock, files that changed recently can belong to an install that is still running.
+	tempList, _ := os.ReadDir(os.TempDir())
+	for _, dirEntry := range tempList {
+		if !strings.HasPrefix(dirEntry.Name(), "nvmc-temp-") && !strings.HasPrefix(dirEntry.Name(), "nvmc-download-") {
+			continue
+		}
+		if info, err := dirEntry.Info(); err != nil || time.Since(info.ModTime()) < leftoverTempFileAge {
+			continue
+		}
+		path := filepath.Join(os.TempDir(), dirEntry.Name())
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, "leftovers", path + " was left behind by nvmc", "remove " + path})
+	}
+
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticOk, "leftovers", "no files were left behind by an install that did not finish", ""})
+	}
+	return diagnostics
+}
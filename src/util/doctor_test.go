@@ -0,0 +1,125 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestInstall creates an installation of version that is missing node when withNode is false.
+func writeTestInstall(t *testing.T, version string, withNode bool) string {
+	t.Helper()
+	versionDir, _ := GetVersionPath(version)
+	installationInfo, _ := GetInstallationInfo(version, NativeTarget())
+	binPath := filepath.Join(versionDir, installationInfo.FileNameWithoutExtension, "bin")
+	if runtime.GOOS == "windows" {
+		binPath = filepath.Dir(binPath)
+	}
+	if err := os.MkdirAll(binPath, 0755); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+	if withNode {
+		writeTestFile(t, filepath.Join(binPath, nodeExecutables()[0]), "")
+		if err := os.Chmod(filepath.Join(binPath, nodeExecutables()[0]), 0755); err != nil {
+			t.Fatalf("Failed to chmod node: %v", err)
+		}
+	}
+	return binPath
+}
+
+func findDiagnostic(diagnostics []Diagnostic, check string, level DiagnosticLevel, message string) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Check == check && diagnostic.Level == level && strings.Contains(diagnostic.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDiagnoseHealthyInstallation(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+	binPath := writeTestInstall(t, "v18.2.0", true)
+	symLink, _ := GetSymLinkPath()
+	if err := os.Symlink(binPath, symLink); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	diagnostics := Diagnose(strings.Join([]string{symLink, t.TempDir()}, string(os.PathListSeparator)), "")
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Level != DiagnosticOk {
+			t.Fatalf(`Diagnose() = %v, Wanted only ok`, diagnostics)
+		}
+	}
+}
+
+func TestDiagnoseProblems(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	writeTestInstall(t, "v18.2.0", true)
+	writeTestInstall(t, "v20.11.0", false)
+	stagingDir, _ := GetStagingPath("v20.11.0")
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		t.Fatalf("Failed to create staging: %v", err)
+	}
+	previousDir, _ := GetPreviousVersionPath("v16.20.0")
+	if err := os.MkdirAll(previousDir, 0755); err != nil {
+		t.Fatalf("Failed to create previous version: %v", err)
+	}
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+	t.Setenv("TMP", tempDir)
+	oldDownload, newDownload := filepath.Join(tempDir, "nvmc-download-1-node.tar.xz"), filepath.Join(tempDir, "nvmc-download-2-node.tar.xz")
+	writeTestFile(t, oldDownload, "")
+	writeTestFile(t, newDownload, "")
+	if err := os.Chtimes(oldDownload, time.Now(), time.Now().Add(-2*leftoverTempFileAge)); err != nil {
+		t.Fatalf("Failed to change the modification time: %v", err)
+	}
+	shadowDir := t.TempDir()
+	writeTestFile(t, filepath.Join(shadowDir, nodeExecutables()[0]), "")
+	symLink, _ := GetSymLinkPath()
+
+	diagnostics := Diagnose(strings.Join([]string{shadowDir, symLink}, string(os.PathListSeparator)), "")
+	wanted := []struct {
+		check   string
+		level   DiagnosticLevel
+		message string
+	}{
+		{"symlink", DiagnosticError, "no version is active"},
+		{"path", DiagnosticError, "shadows the active version"},
+		{"installs", DiagnosticError, "v20.11.0 is broken"},
+		{"leftovers", DiagnosticWarning, stagingDir},
+		{"leftovers", DiagnosticWarning, "v16.20.0 was moved to " + previousDir},
+		{"leftovers", DiagnosticWarning, oldDownload},
+	}
+	for _, w := range wanted {
+		if !findDiagnostic(diagnostics, w.check, w.level, w.message) {
+			t.Fatalf(`Diagnose() = %v, Wanted %s %s %q`, diagnostics, w.check, w.level, w.message)
+		}
+	}
+	// The download of an install that is still running, and the lock files that Diagnose checks, are left alone.
+	if findDiagnostic(diagnostics, "leftovers", DiagnosticWarning, newDownload) {
+		t.Fatalf(`Diagnose() = %v, Wanted %s to not be reported`, diagnostics, newDownload)
+	}
+	lockPath, _ := GetLockPath(VersionLockName("v20.11.0"))
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf(`Diagnose() created %s, error = %v`, lockPath, err)
+	}
+}
+
+func TestDiagnosePathWithoutNvmc(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	diagnostics := Diagnose(t.TempDir(), "")
+	if !findDiagnostic(diagnostics, "path", DiagnosticError, "is not in PATH") {
+		t.Fatalf(`Diagnose() = %v, Wanted nvmc missing from PATH`, diagnostics)
+	}
+
+	shellBinPath := t.TempDir()
+	diagnostics = Diagnose(shellBinPath, shellBinPath)
+	if findDiagnostic(diagnostics, "path", DiagnosticError, "is not in PATH") {
+		t.Fatalf(`Diagnose() = %v, Wanted the bin path of nvmc shell to be accepted`, diagnostics)
+	}
+}
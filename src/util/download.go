@@ -1,44 +1,416 @@
 package util
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-func Download(url string, destHandle io.Writer) error {
-	req, err := http.NewRequest("GET", url, nil)
+// ErrNotFound matches an HTTPStatusError for a file that does not exist on the mirror.
+var ErrNotFound = errors.New("not found on the mirror")
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// errRestartedDownload is returned by DownloadFile when the server sent the whole file instead of the remaining bytes,
+// and the tee that already received the bytes of the partial download cannot be reset. The partial download is
+// discarded, so downloading the file again starts from the beginning.
+var errRestartedDownload = errors.New("the download restarted from the beginning")
+
+// HTTPStatusError is returned when the mirror responds with a status other than success.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay requested by the Retry-After header, zero when not set.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.StatusCode == http.StatusNotFound {
+		return e.URL + " was not found on the mirror (404)"
+	}
+	return e.URL + " responded with " + strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+}
+
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrNotFound && (e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone)
+}
+
+// transient reports whether the request may succeed if it is retried.
+func (e *HTTPStatusError) transient() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// destinationError is a failure writing the downloaded file, which is never retried.
+type destinationError struct {
+	err error
+}
+
+func (e *destinationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *destinationError) Unwrap() error {
+	return e.err
+}
+
+type DownloadOptions struct {
+	// FollowRedirects follows 3xx responses to their Location, otherwise a redirect is an error.
+	FollowRedirects bool
+	// MaxRedirects is the number of redirects followed before failing.
+	MaxRedirects int
+	// ConnectTimeout limits establishing the connection, including the TLS handshake. Zero means no limit.
+	ConnectTimeout time.Duration
+	// ReadTimeout limits waiting for the response headers and for each read of the body. Zero means no limit.
+	ReadTimeout time.Duration
+	// Retries is the number of times a transient failure is retried.
+	Retries int
+	// RetryDelay is the delay before the first retry, it doubles after each retry.
+	RetryDelay time.Duration
+	// CABundle is a PEM file with certificates trusted in addition to the system certificates.
+	CABundle string
+	// AuthToken is sent as a bearer token. Takes precedence over AuthUser and AuthPassword.
+	AuthToken string
+	// AuthUser and AuthPassword are sent as basic authentication when AuthUser is set.
+	AuthUser     string
+	AuthPassword string
+	// Reporter receives the progress of the download, nil to not report progress.
+	Reporter Reporter
+	// Offline reads files from the offline store instead of the network, see ImportBundle. Archives are only
+	// available from the cache, so downloading a file fails with an OfflineError.
+	Offline bool
+}
+
+// Download writes the file at url to destHandle. Transient failures are retried, a destHandle that already
+// received part of the file is rewound when it is an *os.File or has a Reset method, otherwise it is not retried.
+// Proxies are read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. In offline mode, the file
+// is read from the offline store.
+func Download(url string, destHandle io.Writer, opts DownloadOptions) error {
+	if opts.Offline {
+		return readOffline(url, destHandle)
+	}
+	client, err := newHttpClient(opts)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("User-Agent", "nvmc-"+VERSION)
+	var startOffset int64
+	if file, ok := destHandle.(*os.File); ok {
+		if startOffset, err = file.Seek(0, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	restart := func() error {
+		if !resetDestination(destHandle, startOffset) {
+			return errors.New("unable to restart the download of " + url)
+		}
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		written, err := downloadOnce(client, url, destHandle, 0, restart, opts)
+		if err == nil {
+			return nil
+		}
+		if attempt >= opts.Retries || !isTransient(err) {
+			return err
+		}
+		if written > 0 && restart() != nil {
+			return err
+		}
+
+		time.Sleep(retryDelay(opts.RetryDelay, attempt, err))
+	}
+}
 
-	response, err := http.DefaultClient.Do(req)
+// DownloadFile downloads the file at url to path. When path already exists, it is treated as a partial
+// download and only the remaining bytes are requested with a Range request. Retries resume the same way.
+// A server that does not support Range requests restarts the download from the beginning.
+// When tee is not nil, the whole contents of the file are also written to it, including the bytes of a partial
+// download. Restarting the download resets tee, which fails with errRestartedDownload when tee does not have a Reset
+// method.
+func DownloadFile(url string, path string, tee io.Writer, opts DownloadOptions) error {
+	if opts.Offline {
+		return &OfflineError{url}
+	}
+	client, err := newHttpClient(opts)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
 
-	switch response.StatusCode {
-	case 200:
-		if _, err = io.Copy(destHandle, response.Body); err != nil {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dest := &teeFile{file: file, tee: tee}
+	restart := func() error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := file.Truncate(0); err != nil {
 			return err
 		}
+		dest.written = 0
+		if tee != nil && !resetDestination(tee, 0) {
+			return errRestartedDownload
+		}
 		return nil
-	case 300:
-		fallthrough
-	case 302:
-		fallthrough
-	case 307:
-		redirectUrl := response.Header.Get("Location")
-		if len(redirectUrl) > 0 {
-			return Download(redirectUrl, destHandle)
-		}
-		return errors.New("300, 302, and 307 status codes must have a Location header")
+	}
+
+	for attempt := 0; ; attempt++ {
+		offset, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if err := dest.catchUp(offset); err != nil {
+			return err
+		}
+		_, err = downloadOnce(client, url, dest, offset, restart, opts)
+		if err == nil {
+			return nil
+		}
+		if attempt >= opts.Retries || !isTransient(err) {
+			return err
+		}
+
+		time.Sleep(retryDelay(opts.RetryDelay, attempt, err))
+	}
+}
+
+func newHttpClient(opts DownloadOptions) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.ReadTimeout,
+	}
+
+	if len(opts.CABundle) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		bundle, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, errors.New("unable to read CA bundle: " + err.Error())
+		}
+		if !rootCAs.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("CA bundle " + opts.CABundle + " does not contain any PEM certificates")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !opts.FollowRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > opts.MaxRedirects {
+				return errTooManyRedirects
+			}
+			return nil
+		},
+	}, nil
+}
+
+// downloadOnce makes a single request for url, starting at offset. restart is called when the server sends the
+// whole file instead of the requested range.
+func downloadOnce(client *http.Client, url string, destHandle io.Writer, offset int64, restart func() error, opts DownloadOptions) (int64, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("User-Agent", "nvmc-"+VERSION)
+	if len(opts.AuthToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+opts.AuthToken)
+	} else if len(opts.AuthUser) > 0 {
+		req.SetBasicAuth(opts.AuthUser, opts.AuthPassword)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusPartialContent && !strings.HasPrefix(response.Header.Get("Content-Range"), "bytes "+strconv.FormatInt(offset, 10)+"-") {
+		return 0, errors.New(url + " responded with an unexpected range " + response.Header.Get("Content-Range"))
+	}
+	if response.StatusCode == http.StatusOK && offset > 0 {
+		if err := restart(); err != nil {
+			return 0, err
+		}
+		offset = 0
+	}
+
+	switch {
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial download is already complete.
+		return 0, nil
+	case response.StatusCode == http.StatusOK || (response.StatusCode == http.StatusPartialContent && offset > 0):
+		body := io.Reader(response.Body)
+		var timedOut atomic.Bool
+		if opts.ReadTimeout > 0 {
+			timer := time.AfterFunc(opts.ReadTimeout, func() {
+				timedOut.Store(true)
+				cancel()
+			})
+			defer timer.Stop()
+			body = &idleTimeoutReader{response.Body, timer, opts.ReadTimeout}
+		}
+
+		dest := io.Writer(&destinationWriter{destHandle})
+		var tracker *progressTracker
+		if opts.Reporter != nil {
+			total := int64(-1)
+			if response.ContentLength >= 0 {
+				total = offset + response.ContentLength
+			}
+			tracker = newProgressTracker(opts.Reporter, ProgressDownload, path.Base(req.URL.Path), offset, total)
+			dest = &progressWriter{dest, tracker}
+		}
+
+		written, err := io.Copy(dest, body)
+		if err != nil && timedOut.Load() {
+			return written, errors.New("no data received from " + url + " for " + opts.ReadTimeout.String())
+		}
+		if err == nil && tracker != nil {
+			tracker.done()
+		}
+		return written, err
+	case response.StatusCode >= 300 && response.StatusCode < 400 && !opts.FollowRedirects:
+		return 0, errors.New(url + " redirects (" + strconv.Itoa(response.StatusCode) + ") to " + response.Header.Get("Location") + ", enable --follow-redirects to follow it")
 	default:
+		statusErr := &HTTPStatusError{URL: url, StatusCode: response.StatusCode}
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return 0, statusErr
+	}
+}
 
+func isTransient(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.transient()
 	}
+	var destErr *destinationError
+	if errors.As(err, &destErr) || errors.Is(err, errTooManyRedirects) || errors.Is(err, errRestartedDownload) {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+	// Anything else is a network failure.
+	return true
+}
+
+func retryDelay(baseDelay time.Duration, attempt int, err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+	return baseDelay << attempt
+}
+
+// resetDestination discards a partially written download so that it can be retried.
+func resetDestination(destHandle io.Writer, startOffset int64) bool {
+	switch dest := destHandle.(type) {
+	case *os.File:
+		if _, err := dest.Seek(startOffset, io.SeekStart); err != nil {
+			return false
+		}
+		return dest.Truncate(startOffset) == nil
+	case interface{ Reset() }:
+		dest.Reset()
+		return true
+	default:
+		return false
+	}
+}
+
+// teeFile writes to file and tee, tracking how many bytes of file were written to tee.
+type teeFile struct {
+	file    *os.File
+	tee     io.Writer
+	written int64
+}
 
+func (t *teeFile) Write(p []byte) (int, error) {
+	n, err := t.file.Write(p)
+	if t.tee != nil && n > 0 {
+		if _, err := t.tee.Write(p[:n]); err != nil {
+			return n, err
+		}
+		t.written += int64(n)
+	}
+	return n, err
+}
+
+// catchUp writes the bytes of file up to offset that were not written to tee yet, e.g. from a partial download.
+func (t *teeFile) catchUp(offset int64) error {
+	if t.tee == nil || t.written >= offset {
+		return nil
+	}
+	if _, err := io.Copy(t.tee, io.NewSectionReader(t.file, t.written, offset-t.written)); err != nil {
+		return err
+	}
+	t.written = offset
 	return nil
 }
+
+type destinationWriter struct {
+	w io.Writer
+}
+
+func (w *destinationWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, &destinationError{err}
+	}
+	return n, nil
+}
+
+// idleTimeoutReader restarts timer after every read, so the timer only fires when reading stalls.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.timer.Reset(r.timeout)
+	return n, err
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/Masterminds/semver/v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexEntry is a single release listed in the index.json published at the root of the download URL.
+type IndexEntry struct {
+	Version  string   `json:"version"`
+	Date     string   `json:"date"`
+	Files    []string `json:"files"`
+	Npm      string   `json:"npm"`
+	V8       string   `json:"v8"`
+	Lts      Lts      `json:"lts"`
+	Security bool     `json:"security"`
+}
+
+// Lts is the codename of an LTS release, or empty when the release is not LTS.
+// index.json encodes non LTS releases as false and LTS releases as the codename.
+type Lts string
+
+func (l *Lts) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("false")) || bytes.Equal(data, []byte("null")) {
+		*l = ""
+		return nil
+	}
+	var codename string
+	if err := json.Unmarshal(data, &codename); err != nil {
+		return errors.New("unable to parse lts value " + string(data))
+	}
+	*l = Lts(codename)
+	return nil
+}
+
+func (l Lts) MarshalJSON() ([]byte, error) {
+	if l == "" {
+		return []byte("false"), nil
+	}
+	return json.Marshal(string(l))
+}
+
+// FetchIndex downloads and parses <downloadUrl>/index.json, sorted from oldest to newest version. The downloaded
+// index is cached for ReadCachedIndex.
+func FetchIndex(downloadUrl string, opts DownloadOptions) ([]IndexEntry, error) {
+	buf := new(bytes.Buffer)
+	if err := Download(strings.TrimSuffix(downloadUrl, "/")+"/index.json", buf, opts); err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseIndex(buf.Bytes())
+	if err == nil && !opts.Offline {
+		// The cache is only an optimization for lookups without network access.
+		if indexPath, err := getCachedIndexPath(downloadUrl); err == nil {
+			_ = WriteFileAtomic(indexPath, buf.Bytes())
+		}
+	}
+	return entries, err
+}
+
+// ReadCachedIndex returns the index of downloadUrl from the last time FetchIndex downloaded it, without accessing
+// the network.
+func ReadCachedIndex(downloadUrl string) ([]IndexEntry, error) {
+	indexPath, err := getCachedIndexPath(downloadUrl)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("the index of " + downloadUrl + " was not downloaded yet, run nvmc ls-remote to download it")
+	} else if err != nil {
+		return nil, err
+	}
+	return ParseIndex(contents)
+}
+
+// getCachedIndexPath returns where the index of downloadUrl is cached, <NVMC_HOME>/index/<host>/<path>/index.json.
+func getCachedIndexPath(downloadUrl string) (string, error) {
+	name, err := OfflineFileName(strings.TrimSuffix(downloadUrl, "/") + "/index.json")
+	if err != nil {
+		return "", err
+	}
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "index", filepath.FromSlash(name)), nil
+}
+
+// ParseIndex parses the contents of an index.json file, sorted from oldest to newest version.
+// Entries with a version that can not be parsed are dropped.
+func ParseIndex(data []byte) ([]IndexEntry, error) {
+	entries := make([]IndexEntry, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New("unable to parse index.json: " + err.Error())
+	}
+
+	entriesByVersion := make(map[string]IndexEntry, len(entries))
+	semverVersions := make([]*semver.Version, 0, len(entries))
+	for _, entry := range entries {
+		semverVersion, err := semver.NewVersion(entry.Version)
+		if err != nil {
+			continue
+		}
+		if _, found := entriesByVersion[semverVersion.String()]; found {
+			continue
+		}
+		entriesByVersion[semverVersion.String()] = entry
+		semverVersions = append(semverVersions, semverVersion)
+	}
+
+	sort.Sort(semver.Collection(semverVersions))
+
+	sorted := make([]IndexEntry, len(semverVersions))
+	for i, semverVersion := range semverVersions {
+		sorted[i] = entriesByVersion[semverVersion.String()]
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseIndexSortsAndParsesLts(t *testing.T) {
+	data := []byte(`[
+		{"version":"v20.11.0","date":"2024-01-09","files":["linux-x64"],"npm":"10.2.4","v8":"11.3.244.8","lts":"Iron","security":false},
+		{"version":"v18.2.0","date":"2022-05-17","files":["linux-x64"],"npm":"8.9.0","v8":"10.1.124.8","lts":false,"security":true},
+		{"version":"not-a-version","lts":false}
+	]`)
+	entries, err := ParseIndex(data)
+	if err != nil {
+		t.Fatalf(`ParseIndex() error = %v`, err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf(`len(ParseIndex()) = %d, Wanted = %d`, len(entries), 2)
+	}
+	if entries[0].Version != "v18.2.0" || entries[1].Version != "v20.11.0" {
+		t.Fatalf(`ParseIndex() order = %q, %q, Wanted = %q, %q`, entries[0].Version, entries[1].Version, "v18.2.0", "v20.11.0")
+	}
+	if entries[0].Lts != "" || !entries[0].Security {
+		t.Fatalf(`ParseIndex() v18.2.0 lts = %q, security = %v, Wanted = %q, %v`, entries[0].Lts, entries[0].Security, "", true)
+	}
+	if entries[1].Lts != "Iron" {
+		t.Fatalf(`ParseIndex() v20.11.0 lts = %q, Wanted = %q`, entries[1].Lts, "Iron")
+	}
+}
+
+func TestParseIndexErrorOnInvalidJson(t *testing.T) {
+	if _, err := ParseIndex([]byte(`{`)); err == nil {
+		t.Fatalf(`ParseIndex("{") error = nil, Wanted an error`)
+	}
+}
+
+func TestReadCachedIndex(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"version":"v20.11.0","lts":"Iron"}]`))
+	}))
+	defer server.Close()
+
+	if _, err := ReadCachedIndex(server.URL); err == nil {
+		t.Fatalf(`ReadCachedIndex() before FetchIndex() error = nil, Wanted an error`)
+	}
+	if _, err := FetchIndex(server.URL, testDownloadOptions()); err != nil {
+		t.Fatalf(`FetchIndex() error = %v`, err)
+	}
+	server.Close()
+
+	entries, err := ReadCachedIndex(server.URL)
+	if err != nil || len(entries) != 1 || entries[0].Lts != "Iron" {
+		t.Fatalf(`ReadCachedIndex() = %v, %v, Wanted v20.11.0 with lts Iron`, entries, err)
+	}
+}
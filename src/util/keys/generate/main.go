@@ -0,0 +1,92 @@
+// Command generate downloads the Node.js release keys listed in a fingerprints file from the
+// nodejs/release-keys repository and writes them as a single armored keyring, which is embedded into nvmc.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"nvmc/util"
+	"os"
+	"strings"
+	"time"
+)
+
+const keyUrl = "https://raw.githubusercontent.com/nodejs/release-keys/HEAD/keys/"
+
+func main() {
+	fingerprintsPath := flag.String("fingerprints", "keys/fingerprints.txt", "File with one fingerprint per line.")
+	outPath := flag.String("out", "keys/nodejs.asc", "Armored keyring to write.")
+	flag.Parse()
+
+	if err := generate(*fingerprintsPath, *outPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(fingerprintsPath string, outPath string) error {
+	fingerprints, err := readFingerprints(fingerprintsPath)
+	if err != nil {
+		return err
+	}
+
+	opts := util.DownloadOptions{FollowRedirects: true, MaxRedirects: 10, Retries: 3, RetryDelay: time.Second}
+	keyring := openpgp.EntityList{}
+	for _, fingerprint := range fingerprints {
+		buf := new(bytes.Buffer)
+		if err := util.Download(keyUrl+fingerprint+".asc", buf, opts); err != nil {
+			return err
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(buf)
+		if err != nil {
+			return errors.New("unable to read key " + fingerprint + ": " + err.Error())
+		}
+		if len(entities) != 1 || strings.ToUpper(hex.EncodeToString(entities[0].PrimaryKey.Fingerprint)) != fingerprint {
+			return errors.New("downloaded key does not match fingerprint " + fingerprint)
+		}
+		keyring = append(keyring, entities[0])
+	}
+
+	out := new(bytes.Buffer)
+	writer, err := armor.Encode(out, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+	for _, entity := range keyring {
+		if err := entity.Serialize(writer); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outPath, append(out.Bytes(), '\n'), 0644)
+}
+
+func readFingerprints(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fingerprints := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fingerprint, _, _ := strings.Cut(line, " ")
+		fingerprints = append(fingerprints, strings.ToUpper(fingerprint))
+	}
+
+	return fingerprints, scanner.Err()
+}
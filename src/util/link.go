@@ -0,0 +1,78 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// linkRoot is the Root of a linked version.
+const linkRoot = "link"
+
+// FindLinkedNode returns the node executable of the external build in dir, and whether dir is a prefix with a bin
+// directory, as written by make install. Otherwise dir is the directory of the executable, e.g. out/Release of a
+// node checkout.
+func FindLinkedNode(dir string) (string, bool, error) {
+	if runtime.GOOS == "windows" {
+		node := filepath.Join(dir, "node.exe")
+		if _, err := os.Stat(node); err != nil {
+			return "", false, errors.New("unable to find node.exe in " + dir)
+		}
+		return node, false, nil
+	}
+
+	for _, prefix := range []bool{true, false} {
+		node := filepath.Join(dir, "node")
+		if prefix {
+			node = filepath.Join(dir, "bin", "node")
+		}
+		if info, err := os.Stat(node); err == nil && !info.IsDir() {
+			return node, prefix, nil
+		}
+	}
+	return "", false, errors.New("unable to find node in " + dir + " or " + filepath.Join(dir, "bin"))
+}
+
+// StageLink writes a linked version named name for the external build in dir, of version, into tempDir. The root of
+// the version is a symlink to dir when it is a prefix, or a directory with a bin symlink to dir, so that GetBinPath
+// works the same as for installed versions.
+func StageLink(tempDir string, name string, dir string, version string) (*InstallMetadata, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	_, prefix, err := FindLinkedNode(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(tempDir, linkRoot)
+	if prefix || runtime.GOOS == "windows" {
+		err = os.Symlink(dir, root)
+	} else if err = os.Mkdir(root, 0755); err == nil {
+		err = os.Symlink(dir, filepath.Join(root, "bin"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	target := NativeTarget()
+	metadata := &InstallMetadata{
+		Version:     version,
+		Root:        linkRoot,
+		Platform:    target.Platform,
+		Arch:        target.Arch,
+		InstalledAt: time.Now().UTC(),
+		NvmcVersion: VERSION,
+		Aliases:     []string{},
+		Files:       []string{},
+		LinkName:    name,
+		LinkedPath:  dir,
+	}
+	if err := WriteInstallMetadata(tempDir, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFindLinkedNode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows builds do not have a bin directory")
+	}
+	prefixDir := t.TempDir()
+	writeTestFile(t, filepath.Join(prefixDir, "bin", "node"), "node")
+	releaseDir := t.TempDir()
+	writeTestFile(t, filepath.Join(releaseDir, "node"), "node")
+
+	tests := []struct {
+		dir    string
+		node   string
+		prefix bool
+	}{
+		{prefixDir, filepath.Join(prefixDir, "bin", "node"), true},
+		{releaseDir, filepath.Join(releaseDir, "node"), false},
+	}
+	for _, test := range tests {
+		node, prefix, err := FindLinkedNode(test.dir)
+		if err != nil || node != test.node || prefix != test.prefix {
+			t.Fatalf(`FindLinkedNode(%q) = %q, %v, %v, Wanted = %q, %v`, test.dir, node, prefix, err, test.node, test.prefix)
+		}
+	}
+
+	if _, _, err := FindLinkedNode(t.TempDir()); err == nil {
+		t.Fatalf(`FindLinkedNode() of an empty directory error = nil, Wanted an error`)
+	}
+}
+
+func TestStageLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires developer mode on windows")
+	}
+	t.Setenv("NVMC_HOME", t.TempDir())
+	releaseDir := t.TempDir()
+	writeTestFile(t, filepath.Join(releaseDir, "node"), "node")
+
+	versionDir, _ := GetVersionPath("debug")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+	metadata, err := StageLink(versionDir, "debug", releaseDir, "v22.0.0-pre")
+	if err != nil || metadata.Name() != "debug" || !metadata.IsLinked() || metadata.LinkedPath != releaseDir {
+		t.Fatalf(`StageLink() = %v, %v, Wanted a link named debug to %q`, metadata, err, releaseDir)
+	}
+
+	node, err := GetNodePath("debug")
+	if err != nil {
+		t.Fatalf(`GetNodePath() error = %v`, err)
+	}
+	if contents, err := os.ReadFile(node); err != nil || string(contents) != "node" {
+		t.Fatalf(`GetNodePath() = %q, which is not the linked node, %v`, node, err)
+	}
+	if _, err := VerifyInstall("debug"); !errors.Is(err, ErrLinkedVersion) {
+		t.Fatalf(`VerifyInstall() error = %v, Wanted = %v`, err, ErrLinkedVersion)
+	}
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("locked")
+
+// FileLock is an exclusive lock on a file, held until Unlock is called or the process exits.
+// It excludes other processes, so that concurrent nvmc commands do not modify the same files.
+type FileLock struct {
+	file *os.File
+}
+
+// GetLockPath returns the path of the lock file name, e.g. the version for the lock of an installation.
+func GetLockPath(name string) (string, error) {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "locks", name+".lock"), nil
+}
+
+// VersionLockName returns the name of the lock of an installed version, which is held while it is installed, replaced
+// or uninstalled. The name is prefixed, so that a version or link name never shares the lock file of another lock,
+// e.g. a link named nodejs.
+func VersionLockName(version string) string {
+	return "version-" + version
+}
+
+// LockFile returns the lock of the lock file name, creating it when it does not exist. When another process holds
+// the lock, waiting is called before blocking until the lock is released.
+func LockFile(name string, waiting func()) (*FileLock, error) {
+	file, err := openLockFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tryLock(file)
+	if errors.Is(err, errLocked) {
+		if waiting != nil {
+			waiting()
+		}
+		err = lock(file)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.New("unable to lock " + file.Name() + ": " + err.Error())
+	}
+
+	return &FileLock{file}, nil
+}
+
+// TryLockFile returns the lock of the lock file name, or nil when another process holds the lock.
+func TryLockFile(name string) (*FileLock, error) {
+	file, err := openLockFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tryLock(file)
+	if err != nil {
+		_ = file.Close()
+		if errors.Is(err, errLocked) {
+			return nil, nil
+		}
+		return nil, errors.New("unable to lock " + file.Name() + ": " + err.Error())
+	}
+
+	return &FileLock{file}, nil
+}
+
+// IsLocked returns whether another process holds the lock of the lock file name. Unlike TryLockFile, the lock file
+// is not created when it does not exist, e.g. for a diagnosis that must not change NVMC_HOME.
+func IsLocked(name string) (bool, error) {
+	path, err := GetLockPath(name)
+	if err != nil {
+		return false, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	err = tryLock(file)
+	if errors.Is(err, errLocked) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, unlock(file)
+}
+
+func openLockFile(name string) (*os.File, error) {
+	path, err := GetLockPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+}
+
+// Unlock releases the lock. The lock file is kept, removing it could let two processes lock different files.
+func (l *FileLock) Unlock() error {
+	if err := unlock(l.file); err != nil {
+		_ = l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
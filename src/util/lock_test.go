@@ -0,0 +1,84 @@
+package util
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLockFileWaitsForOtherLock(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	lock, err := LockFile("v18.2.0", nil)
+	if err != nil {
+		t.Fatalf(`LockFile() error = %v`, err)
+	}
+
+	waiting := make(chan bool, 1)
+	locked := make(chan error, 1)
+	go func() {
+		otherLock, err := LockFile("v18.2.0", func() { waiting <- true })
+		if err == nil {
+			err = otherLock.Unlock()
+		}
+		locked <- err
+	}()
+
+	select {
+	case <-waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatalf(`LockFile() of a held lock did not wait`)
+	}
+	select {
+	case err := <-locked:
+		t.Fatalf(`LockFile() of a held lock = %v, Wanted it to block`, err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf(`Unlock() error = %v`, err)
+	}
+	if err := <-locked; err != nil {
+		t.Fatalf(`LockFile() after Unlock() error = %v`, err)
+	}
+}
+
+func TestLockFileDoesNotWaitForOtherNames(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	lock, err := LockFile("v18.2.0", nil)
+	if err != nil {
+		t.Fatalf(`LockFile() error = %v`, err)
+	}
+	defer lock.Unlock()
+
+	otherLock, err := LockFile("v20.11.0", func() { t.Fatalf(`LockFile() waited for the lock of another name`) })
+	if err != nil {
+		t.Fatalf(`LockFile() error = %v`, err)
+	}
+	_ = otherLock.Unlock()
+}
+
+func TestIsLocked(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+
+	if locked, err := IsLocked("v18.2.0"); err != nil || locked {
+		t.Fatalf(`IsLocked() without a lock file = %v, %v, Wanted = false`, locked, err)
+	}
+	path, _ := GetLockPath("v18.2.0")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf(`IsLocked() created %s, error = %v`, path, err)
+	}
+
+	lock, err := LockFile("v18.2.0", nil)
+	if err != nil {
+		t.Fatalf(`LockFile() error = %v`, err)
+	}
+	if locked, err := IsLocked("v18.2.0"); err != nil || !locked {
+		t.Fatalf(`IsLocked() of a held lock = %v, %v, Wanted = true`, locked, err)
+	}
+	_ = lock.Unlock()
+	if locked, err := IsLocked("v18.2.0"); err != nil || locked {
+		t.Fatalf(`IsLocked() after Unlock() = %v, %v, Wanted = false`, locked, err)
+	}
+}
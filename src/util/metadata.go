@@ -0,0 +1,175 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Masterminds/semver/v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const installMetadataFileName = "install.json"
+
+// InstallMetadata records where an installed version came from. It is written to install.json in the directory of
+// the version. Versions installed by an older nvmc do not have it, see ReadInstallMetadata.
+type InstallMetadata struct {
+	Version string `json:"version"`
+	// Root is the directory of the extracted archive, relative to the directory of the version.
+	Root     string `json:"root"`
+	Platform string `json:"platform"`
+	Arch     string `json:"arch"`
+	// Libc is empty for the default C library of the platform, or LibcMusl.
+	Libc          string        `json:"libc,omitempty"`
+	Mirror        string        `json:"mirror"`
+	SourceUrl     string        `json:"sourceUrl"`
+	ArchiveFormat ArchiveFormat `json:"archiveFormat"`
+	// Checksum is the checksum the archive was verified with, prefixed with the algorithm, e.g. sha256:<hex>.
+	// It is empty when checksum validation was skipped.
+	Checksum    string    `json:"checksum,omitempty"`
+	InstalledAt time.Time `json:"installedAt"`
+	NvmcVersion string    `json:"nvmcVersion"`
+	Aliases     []string  `json:"aliases"`
+	// Files are the files of the archive, relative to Root.
+	Files []string `json:"files"`
+	// FileHashes are the hashes of Files when they were installed, see HashFiles. Versions installed before
+	// nvmc recorded them do not have it.
+	FileHashes map[string]string `json:"fileHashes,omitempty"`
+	// FromSource is set when the version was built from source, with ConfigureFlags passed to ./configure.
+	// Files are then the installed files.
+	FromSource     bool     `json:"fromSource,omitempty"`
+	ConfigureFlags []string `json:"configureFlags,omitempty"`
+	// LinkName is the name of an external build registered with nvmc link, and LinkedPath its directory. Root is
+	// then a symlink to LinkedPath, or a directory with a bin symlink to it, and there are no Files.
+	LinkName   string `json:"linkName,omitempty"`
+	LinkedPath string `json:"linkedPath,omitempty"`
+}
+
+// Target returns the target the version was installed for.
+func (m *InstallMetadata) Target() Target {
+	return Target{m.Platform, m.Arch, m.Libc}
+}
+
+// Name returns the name of the directory of the installed version, see InstallName. It is the name given to
+// nvmc link for a linked version.
+func (m *InstallMetadata) Name() string {
+	if m.IsLinked() {
+		return m.LinkName
+	}
+	return InstallName(m.Version, m.Target())
+}
+
+// IsLinked returns whether the version is an external build registered with nvmc link.
+func (m *InstallMetadata) IsLinked() bool {
+	return len(m.LinkName) > 0
+}
+
+// IsLegacy returns whether the version was installed by an older nvmc, which did not record any metadata.
+func (m *InstallMetadata) IsLegacy() bool {
+	return m.InstalledAt.IsZero()
+}
+
+func GetInstallMetadataPath(version string) (string, error) {
+	versionDir, err := GetVersionPath(version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(versionDir, installMetadataFileName), nil
+}
+
+// ReadInstallMetadata returns the metadata of the installed version. For a version installed by an older nvmc,
+// it returns metadata with only the version, the current os and arch, and the root that nvmc has always used.
+func ReadInstallMetadata(version string) (*InstallMetadata, error) {
+	metadataPath, err := GetInstallMetadataPath(version)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(metadataPath)
+	if errors.Is(err, os.ErrNotExist) {
+		versionDir := filepath.Dir(metadataPath)
+		if _, err := os.Stat(versionDir); err != nil {
+			return nil, err
+		}
+		installationInfo, err := GetInstallationInfo(version, NativeTarget())
+		if err != nil {
+			return nil, err
+		}
+		return &InstallMetadata{Version: version, Root: installationInfo.FileNameWithoutExtension, Platform: installationInfo.Platform, Arch: installationInfo.Arch, Aliases: []string{}, Files: []string{}}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	metadata := &InstallMetadata{}
+	if err := json.Unmarshal(contents, metadata); err != nil {
+		return nil, errors.New("unable to parse " + metadataPath + ": " + err.Error())
+	}
+	return metadata, nil
+}
+
+// WriteInstallMetadata writes metadata to install.json in dir, replacing it atomically.
+func WriteInstallMetadata(dir string, metadata *InstallMetadata) error {
+	contents, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(dir, installMetadataFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(append(contents, '\n')); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), filepath.Join(dir, installMetadataFileName))
+}
+
+// ListInstallMetadata returns the metadata of every installed version, sorted by version. A directory in the
+// versions directory is an installed version when it has install.json, or when an older nvmc installed it.
+// Names of directories that look like versions, but are not valid versions, are returned in invalid.
+func ListInstallMetadata() (installed []*InstallMetadata, invalid []string, err error) {
+	installed, invalid = make([]*InstallMetadata, 0), make([]string, 0)
+	versionsDir, err := GetVersionsPath()
+	if err != nil {
+		return installed, invalid, err
+	}
+
+	dirList, err := os.ReadDir(versionsDir)
+	if err != nil {
+		return installed, invalid, err
+	}
+
+	versions := make(map[*InstallMetadata]*semver.Version)
+	for _, dirEntry := range dirList {
+		// Hidden directories are not versions, e.g. a version that is moved aside while it is replaced.
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		metadata, err := ReadInstallMetadata(dirEntry.Name())
+		if err != nil {
+			return installed, invalid, err
+		}
+		if metadata.IsLegacy() && !strings.HasPrefix(dirEntry.Name(), "v") {
+			continue
+		}
+		semverVersion, err := semver.NewVersion(metadata.Version)
+		if err != nil {
+			invalid = append(invalid, dirEntry.Name())
+			continue
+		}
+		versions[metadata] = semverVersion
+		installed = append(installed, metadata)
+	}
+
+	sort.SliceStable(installed, func(i, j int) bool {
+		return versions[installed[i]].LessThan(versions[installed[j]])
+	})
+	return installed, invalid, nil
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteInstallMetadata(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	versionDir, _ := GetVersionPath("v18.2.0")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+
+	metadata := &InstallMetadata{
+		Version:       "v18.2.0",
+		Root:          "node-v18.2.0-linux-x64",
+		Platform:      "linux",
+		Arch:          "x64",
+		Mirror:        "https://nodejs.org/dist",
+		SourceUrl:     "https://nodejs.org/dist/v18.2.0/node-v18.2.0-linux-x64.tar.xz",
+		ArchiveFormat: ArchiveTarXz,
+		Checksum:      "sha256:" + checksumTestSha256,
+		InstalledAt:   time.Date(2022, 5, 17, 12, 0, 0, 0, time.UTC),
+		NvmcVersion:   "1.0.0",
+		Aliases:       []string{"work"},
+		Files:         []string{"bin/node"},
+	}
+	if err := WriteInstallMetadata(versionDir, metadata); err != nil {
+		t.Fatalf(`WriteInstallMetadata() error = %v`, err)
+	}
+
+	actual, err := ReadInstallMetadata("v18.2.0")
+	if err != nil || !reflect.DeepEqual(actual, metadata) {
+		t.Fatalf(`ReadInstallMetadata() = %v, %v, Wanted = %v`, actual, err, metadata)
+	}
+}
+
+func TestReadInstallMetadataOfLegacyVersion(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	versionDir, _ := GetVersionPath("v18.2.0")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+
+	metadata, err := ReadInstallMetadata("v18.2.0")
+	installationInfo, _ := GetInstallationInfo("v18.2.0", NativeTarget())
+	if err != nil || !metadata.IsLegacy() || metadata.Root != installationInfo.FileNameWithoutExtension {
+		t.Fatalf(`ReadInstallMetadata() = %v, %v, Wanted legacy metadata with root %q`, metadata, err, installationInfo.FileNameWithoutExtension)
+	}
+}
+
+func TestListInstallMetadata(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	versionsDir, _ := GetVersionsPath()
+	for _, name := range []string{"v20.11.0", "v18.2.0", "v9.0.0", "vbroken", "backup"} {
+		if err := os.MkdirAll(filepath.Join(versionsDir, name), 0755); err != nil {
+			t.Fatalf("Failed to create version: %v", err)
+		}
+	}
+	metadata := &InstallMetadata{Version: "v20.11.0", InstalledAt: time.Now()}
+	if err := WriteInstallMetadata(filepath.Join(versionsDir, "v20.11.0"), metadata); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	installed, invalid, err := ListInstallMetadata()
+	if err != nil {
+		t.Fatalf(`ListInstallMetadata() error = %v`, err)
+	}
+	versions := make([]string, len(installed))
+	for i, metadata := range installed {
+		versions[i] = metadata.Version
+	}
+	expectVersions := []string{"v9.0.0", "v18.2.0", "v20.11.0"}
+	if !reflect.DeepEqual(versions, expectVersions) || !reflect.DeepEqual(invalid, []string{"vbroken"}) {
+		t.Fatalf(`ListInstallMetadata() = %q, %q, Wanted = %q, %q`, versions, invalid, expectVersions, []string{"vbroken"})
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MirrorFile downloads the file at url to path, unless path already has the contents verified by verifier. An
+// interrupted download is kept as <path>.part and resumed by the next call. Returns whether the file was downloaded.
+func MirrorFile(url string, path string, verifier *ChecksumVerifier, opts DownloadOptions) (bool, error) {
+	if err := verifier.VerifyFile(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		return false, err
+	}
+
+	partialPath := path + ".part"
+	verifier.Reset()
+	if err := DownloadFile(url, partialPath, verifier, opts); err != nil {
+		return false, err
+	}
+	if err := verifier.Verify(); err != nil {
+		if removeErr := os.Remove(partialPath); removeErr != nil {
+			return false, removeErr
+		}
+		return false, err
+	}
+	return true, os.Rename(partialPath, path)
+}
+
+// WriteMirrorIndex writes index.json and index.tab to the mirror in dir, with the entries of indexJson and indexTab
+// for which keep returns true. The entries are kept as published, including the fields that IndexEntry does not
+// have. indexTab is optional, a mirror might not publish it.
+func WriteMirrorIndex(dir string, indexJson []byte, indexTab []byte, keep func(version string) bool) error {
+	entries := make([]json.RawMessage, 0)
+	if err := json.Unmarshal(indexJson, &entries); err != nil {
+		return errors.New("unable to parse index.json: " + err.Error())
+	}
+	kept := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		var versioned struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(entry, &versioned); err != nil {
+			return errors.New("unable to parse index.json: " + err.Error())
+		}
+		if keep(versioned.Version) {
+			compacted := new(bytes.Buffer)
+			if err := json.Compact(compacted, entry); err != nil {
+				return err
+			}
+			kept = append(kept, compacted.Bytes())
+		}
+	}
+	// One entry per line, like the index.json of nodejs.org.
+	contents := append(append([]byte("[\n"), bytes.Join(kept, []byte(",\n"))...), []byte("\n]\n")...)
+	if err := WriteFileAtomic(filepath.Join(dir, "index.json"), contents); err != nil {
+		return err
+	}
+
+	if indexTab == nil {
+		return nil
+	}
+	lines := strings.SplitAfter(string(indexTab), "\n")
+	tab := new(strings.Builder)
+	for i, line := range lines {
+		version, _, _ := strings.Cut(line, "\t")
+		// The first line is the header.
+		if i == 0 || (len(strings.TrimSpace(line)) > 0 && keep(version)) {
+			tab.WriteString(line)
+		}
+	}
+	return WriteFileAtomic(filepath.Join(dir, "index.tab"), []byte(tab.String()))
+}
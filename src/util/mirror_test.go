@@ -0,0 +1,59 @@
+package util
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirrorFile(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeContent(w, r, "node.tar.gz", time.Time{}, bytes.NewReader(cacheTestContents))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "v18.2.0", "node.tar.gz")
+
+	for i, wanted := range []bool{true, false} {
+		downloaded, err := MirrorFile(server.URL, path, cacheTestVerifier(t, cacheTestChecksum()), testDownloadOptions())
+		if err != nil || downloaded != wanted || requests != 1 {
+			t.Fatalf(`MirrorFile() call %d = %v, %v, with %d requests, Wanted = %v with 1 request`, i+1, downloaded, err, requests, wanted)
+		}
+	}
+
+	writeTestFile(t, path, "modified")
+	if downloaded, err := MirrorFile(server.URL, path, cacheTestVerifier(t, cacheTestChecksum()), testDownloadOptions()); err != nil || !downloaded {
+		t.Fatalf(`MirrorFile() of a modified file = %v, %v, Wanted = true`, downloaded, err)
+	}
+	if contents, err := os.ReadFile(path); err != nil || !bytes.Equal(contents, cacheTestContents) {
+		t.Fatalf(`MirrorFile() did not replace the modified file: %v`, err)
+	}
+}
+
+func TestWriteMirrorIndex(t *testing.T) {
+	dir := t.TempDir()
+	indexJson := `[{"version":"v20.11.0","files":["linux-x64"],"uv":"1.46.0","lts":"Iron"},
+{"version":"v18.2.0","files":["linux-x64"],"uv":"1.43.0","lts":false}]`
+	indexTab := "version\tdate\tfiles\n" +
+		"v20.11.0\t2024-01-09\tlinux-x64\n" +
+		"v18.2.0\t2022-05-17\tlinux-x64\n"
+
+	keep := func(version string) bool { return version == "v20.11.0" }
+	if err := WriteMirrorIndex(dir, []byte(indexJson), []byte(indexTab), keep); err != nil {
+		t.Fatalf(`WriteMirrorIndex() error = %v`, err)
+	}
+
+	wantedJson := "[\n{\"version\":\"v20.11.0\",\"files\":[\"linux-x64\"],\"uv\":\"1.46.0\",\"lts\":\"Iron\"}\n]\n"
+	if contents, err := os.ReadFile(filepath.Join(dir, "index.json")); err != nil || string(contents) != wantedJson {
+		t.Fatalf(`WriteMirrorIndex() wrote index.json = %q, %v, Wanted = %q`, contents, err, wantedJson)
+	}
+	wantedTab := "version\tdate\tfiles\nv20.11.0\t2024-01-09\tlinux-x64\n"
+	if contents, err := os.ReadFile(filepath.Join(dir, "index.tab")); err != nil || string(contents) != wantedTab {
+		t.Fatalf(`WriteMirrorIndex() wrote index.tab = %q, %v, Wanted = %q`, contents, err, wantedTab)
+	}
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrOffline matches an OfflineError.
+var ErrOffline = errors.New("not available offline")
+
+// OfflineError is returned in offline mode for a file that was not imported with nvmc bundle import.
+type OfflineError struct {
+	Url string
+}
+
+func (e *OfflineError) Error() string {
+	return e.Url + " is not available offline, import a bundle that has it with nvmc bundle import"
+}
+
+// Is also matches ErrNotFound, so that a missing optional file, e.g. a signature, is handled the same as on a mirror
+// that does not publish it.
+func (e *OfflineError) Is(target error) bool {
+	return target == ErrOffline || target == ErrNotFound
+}
+
+// GetOfflinePath returns the offline store, where nvmc bundle import stores the index.json, sums and signature files
+// of a mirror. The archives are stored in the cache.
+func GetOfflinePath() (string, error) {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "offline"), nil
+}
+
+// OfflineFileName returns the name of the file at fileUrl in the offline store and in bundles, the host and path of
+// the URL, e.g. nodejs.org/dist/index.json.
+func OfflineFileName(fileUrl string) (string, error) {
+	parsed, err := url.Parse(fileUrl)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.Host) == 0 {
+		return "", errors.New("unable to store " + fileUrl + " offline, it is not a URL")
+	}
+	// Ports are separated with an underscore, a colon is not valid in file names on windows.
+	return strings.ReplaceAll(parsed.Host, ":", "_") + strings.TrimSuffix(path.Clean("/"+parsed.Path), "/"), nil
+}
+
+// offlineFilePath returns the path of the file named name in the offline store, see OfflineFileName.
+func offlineFilePath(name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", errors.New("invalid offline file name " + name)
+	}
+	offlineDir, err := GetOfflinePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(offlineDir, filepath.FromSlash(name)), nil
+}
+
+// readOffline writes the file at fileUrl from the offline store to destHandle.
+func readOffline(fileUrl string, destHandle io.Writer) error {
+	name, err := OfflineFileName(fileUrl)
+	if err != nil {
+		return err
+	}
+	filePath, err := offlineFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &OfflineError{fileUrl}
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(destHandle, file)
+	return err
+}
+
+// WriteOfflineFile stores contents as the file named name in the offline store, replacing it atomically.
+func WriteOfflineFile(name string, contents []byte) error {
+	filePath, err := offlineFilePath(name)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(filePath, contents)
+}
+
+// MergeOfflineIndex adds entries to the index.json named name in the offline store, replacing the entries of the
+// same versions. The index is written from newest to oldest version, like the index.json of nodejs.org.
+func MergeOfflineIndex(name string, entries []IndexEntry) error {
+	filePath, err := offlineFilePath(name)
+	if err != nil {
+		return err
+	}
+
+	merged := slices.Clone(entries)
+	contents, err := os.ReadFile(filePath)
+	if err == nil {
+		existing, err := ParseIndex(contents)
+		if err != nil {
+			return errors.New("unable to merge into " + filePath + ", " + err.Error())
+		}
+		for _, entry := range existing {
+			if !slices.ContainsFunc(entries, func(e IndexEntry) bool { return e.Version == entry.Version }) {
+				merged = append(merged, entry)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if contents, err = json.Marshal(merged); err != nil {
+		return err
+	}
+	if merged, err = ParseIndex(contents); err != nil {
+		return err
+	}
+	slices.Reverse(merged)
+	if contents, err = json.MarshalIndent(merged, "", "  "); err != nil {
+		return err
+	}
+	return WriteOfflineFile(name, append(contents, '\n'))
+}
+
+// offlineVerifier returns a verifier for the archive named name in the offline store, from the sums file next to it.
+func offlineVerifier(name string) (*ChecksumVerifier, error) {
+	for _, algorithm := range []ChecksumAlgorithm{ChecksumSha256, ChecksumSha512} {
+		sumsPath, err := offlineFilePath(path.Join(path.Dir(name), algorithm.SumsFileName()))
+		if err != nil {
+			return nil, err
+		}
+		contents, err := os.ReadFile(sumsPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sums, err := ParseSums(contents, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		return NewChecksumVerifier(sums, path.Base(name), algorithm)
+	}
+	return nil, errors.New("unable to verify " + name + ", there is no sums file next to it")
+}
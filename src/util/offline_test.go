@@ -0,0 +1,65 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestOfflineFileName(t *testing.T) {
+	tests := map[string]string{
+		"https://nodejs.org/dist/index.json":                     "nodejs.org/dist/index.json",
+		"http://127.0.0.1:8080/node/v18.2.0/SHASUMS256.txt":      "127.0.0.1_8080/node/v18.2.0/SHASUMS256.txt",
+		"https://mirror.example.com/../../etc/passwd":            "mirror.example.com/etc/passwd",
+		"https://unofficial-builds.nodejs.org/download/release/": "unofficial-builds.nodejs.org/download/release",
+	}
+	for fileUrl, wanted := range tests {
+		if name, err := OfflineFileName(fileUrl); err != nil || name != wanted {
+			t.Fatalf(`OfflineFileName(%q) = %q, %v, Wanted = %q`, fileUrl, name, err, wanted)
+		}
+	}
+
+	if _, err := OfflineFileName("index.json"); err == nil {
+		t.Fatalf(`OfflineFileName("index.json") error = nil, Wanted an error`)
+	}
+}
+
+func TestDownloadOffline(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	if err := WriteOfflineFile("nodejs.org/dist/v18.2.0/SHASUMS256.txt", []byte("sums")); err != nil {
+		t.Fatalf(`WriteOfflineFile() error = %v`, err)
+	}
+	opts := testDownloadOptions()
+	opts.Offline = true
+
+	buf := new(bytes.Buffer)
+	if err := Download("https://nodejs.org/dist/v18.2.0/SHASUMS256.txt", buf, opts); err != nil || buf.String() != "sums" {
+		t.Fatalf(`Download() = %q, %v, Wanted = %q`, buf.String(), err, "sums")
+	}
+
+	err := Download("https://nodejs.org/dist/v18.2.0/SHASUMS256.txt.sig", new(bytes.Buffer), opts)
+	if !errors.Is(err, ErrOffline) || !errors.Is(err, ErrNotFound) {
+		t.Fatalf(`Download() error = %v, Wanted = %v`, err, ErrOffline)
+	}
+	if err := DownloadFile("https://nodejs.org/dist/v18.2.0/node-v18.2.0-linux-x64.tar.xz", t.TempDir()+"/node.tar.xz", nil, opts); !errors.Is(err, ErrOffline) {
+		t.Fatalf(`DownloadFile() error = %v, Wanted = %v`, err, ErrOffline)
+	}
+}
+
+func TestMergeOfflineIndex(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	name := "nodejs.org/dist/index.json"
+	if err := MergeOfflineIndex(name, []IndexEntry{{Version: "v18.2.0"}, {Version: "v20.11.0", Lts: "Iron"}}); err != nil {
+		t.Fatalf(`MergeOfflineIndex() error = %v`, err)
+	}
+	if err := MergeOfflineIndex(name, []IndexEntry{{Version: "v20.11.0"}, {Version: "v19.0.0"}}); err != nil {
+		t.Fatalf(`MergeOfflineIndex() error = %v`, err)
+	}
+
+	opts := testDownloadOptions()
+	opts.Offline = true
+	entries, err := FetchIndex("https://nodejs.org/dist", opts)
+	if err != nil || len(entries) != 3 || entries[0].Version != "v18.2.0" || entries[2].Version != "v20.11.0" || entries[2].Lts != "" {
+		t.Fatalf(`FetchIndex() = %v, %v, Wanted v18.2.0, v19.0.0 and v20.11.0 without lts`, entries, err)
+	}
+}
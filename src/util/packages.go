@@ -0,0 +1,200 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// globalPackagePaths returns the directory npm installs global packages to, and the directory it links their
+// executables in, relative to the root of an installed version.
+func globalPackagePaths() (string, string) {
+	if runtime.GOOS == "windows" {
+		return "node_modules", "."
+	}
+	return filepath.Join("lib", "node_modules"), "bin"
+}
+
+// bundledPackages are the global packages that come with node.
+var bundledPackages = []string{"npm", "corepack"}
+
+// GlobalPackage is a package installed with npm install --global.
+type GlobalPackage struct {
+	Name string
+	// Link is the directory of a package installed with npm link, or empty.
+	Link string
+}
+
+// ListGlobalPackages returns the global npm packages of the root of an installed version, except the packages that
+// come with node, e.g. npm.
+func ListGlobalPackages(root string) ([]GlobalPackage, error) {
+	packagesPath, _ := globalPackagePaths()
+	nodeModulesDir := filepath.Join(root, packagesPath)
+	names, err := listPackages(nodeModulesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	globalPackages := make([]GlobalPackage, 0, len(names))
+	for _, name := range names {
+		if slices.Contains(bundledPackages, name) {
+			continue
+		}
+		packageDir := filepath.Join(nodeModulesDir, filepath.FromSlash(name))
+		globalPackage := GlobalPackage{Name: name}
+
+		info, err := os.Lstat(packageDir)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if globalPackage.Link, err = filepath.EvalSymlinks(packageDir); err != nil {
+				return nil, errors.New("unable to find the linked package " + name + ": " + err.Error())
+			}
+		} else if !info.IsDir() {
+			continue
+		}
+
+		// A directory without a package.json is not a package.
+		if _, err := os.Stat(filepath.Join(packageDir, "package.json")); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		globalPackages = append(globalPackages, globalPackage)
+	}
+	return globalPackages, nil
+}
+
+// CopyGlobalPackages copies the global npm packages, and their executables, from the root of an installed version
+// to the root of another installation. Packages and executables that the other installation already has, e.g. npm,
+// are not copied. Returns the names of the copied packages.
+func CopyGlobalPackages(fromRoot string, toRoot string) ([]string, error) {
+	packagesPath, binPath := globalPackagePaths()
+	copied := make([]string, 0)
+
+	packages, err := listPackages(filepath.Join(fromRoot, packagesPath))
+	if err != nil {
+		return copied, err
+	}
+	for _, name := range packages {
+		to := filepath.Join(toRoot, packagesPath, filepath.FromSlash(name))
+		if _, err := os.Lstat(to); err == nil {
+			continue
+		}
+		if err := copyTree(filepath.Join(fromRoot, packagesPath, filepath.FromSlash(name)), to); err != nil {
+			return copied, err
+		}
+		copied = append(copied, name)
+	}
+
+	binList, err := os.ReadDir(filepath.Join(fromRoot, binPath))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return copied, err
+	}
+	for _, dirEntry := range binList {
+		to := filepath.Join(toRoot, binPath, dirEntry.Name())
+		if dirEntry.IsDir() {
+			continue
+		} else if _, err := os.Lstat(to); err == nil {
+			continue
+		}
+		if err := copyTree(filepath.Join(fromRoot, binPath, dirEntry.Name()), to); err != nil {
+			return copied, err
+		}
+	}
+
+	return copied, nil
+}
+
+// listPackages returns the names of the packages in a node_modules directory, including scoped packages, e.g. @scope/name.
+func listPackages(nodeModulesDir string) ([]string, error) {
+	packages := make([]string, 0)
+	dirList, err := os.ReadDir(nodeModulesDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return packages, nil
+	} else if err != nil {
+		return packages, err
+	}
+
+	for _, dirEntry := range dirList {
+		if strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		if !strings.HasPrefix(dirEntry.Name(), "@") || !dirEntry.IsDir() {
+			packages = append(packages, dirEntry.Name())
+			continue
+		}
+		scopeList, err := os.ReadDir(filepath.Join(nodeModulesDir, dirEntry.Name()))
+		if err != nil {
+			return packages, err
+		}
+		for _, scopeEntry := range scopeList {
+			packages = append(packages, dirEntry.Name()+"/"+scopeEntry.Name())
+		}
+	}
+	return packages, nil
+}
+
+// copyTree copies the file, symlink or directory at from to to, keeping the permissions of files and the targets
+// of symlinks.
+func copyTree(from string, to string) error {
+	return filepath.WalkDir(from, func(path string, dirEntry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, relPath)
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			linkname, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), fs.ModePerm); err != nil {
+				return err
+			}
+			return os.Symlink(linkname, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), fs.ModePerm); err != nil {
+				return err
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return errors.New("unable to copy " + path + ", it is not a file, directory or symlink")
+		}
+	})
+}
+
+func copyFile(from string, to string, mode fs.FileMode) error {
+	reader, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	writer, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
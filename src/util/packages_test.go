@@ -0,0 +1,68 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCopyGlobalPackages(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("npm links the executables of global packages with shims on windows")
+	}
+	fromRoot, toRoot := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(fromRoot, "lib", "node_modules", "npm", "package.json"), "old npm")
+	writeTestFile(t, filepath.Join(fromRoot, "lib", "node_modules", "typescript", "bin", "tsc"), "tsc")
+	writeTestFile(t, filepath.Join(fromRoot, "lib", "node_modules", "@angular", "cli", "package.json"), "{}")
+	writeTestFile(t, filepath.Join(fromRoot, "bin", "npm"), "old npm")
+	if err := os.Symlink("../lib/node_modules/typescript/bin/tsc", filepath.Join(fromRoot, "bin", "tsc")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	writeTestFile(t, filepath.Join(toRoot, "lib", "node_modules", "npm", "package.json"), "npm")
+	writeTestFile(t, filepath.Join(toRoot, "bin", "npm"), "npm")
+
+	packages, err := CopyGlobalPackages(fromRoot, toRoot)
+	wanted := []string{"@angular/cli", "typescript"}
+	if err != nil || !reflect.DeepEqual(packages, wanted) {
+		t.Fatalf(`CopyGlobalPackages() = %v, %v, Wanted = %v`, packages, err, wanted)
+	}
+
+	for path, contents := range map[string]string{
+		"lib/node_modules/npm/package.json":          "npm",
+		"lib/node_modules/typescript/bin/tsc":        "tsc",
+		"lib/node_modules/@angular/cli/package.json": "{}",
+		"bin/tsc": "tsc",
+		"bin/npm": "npm",
+	} {
+		actual, err := os.ReadFile(filepath.Join(toRoot, filepath.FromSlash(path)))
+		if err != nil || string(actual) != contents {
+			t.Fatalf(`ReadFile(%q) = %q, %v, Wanted = %q`, path, actual, err, contents)
+		}
+	}
+	if linkname, err := os.Readlink(filepath.Join(toRoot, "bin", "tsc")); err != nil || linkname != "../lib/node_modules/typescript/bin/tsc" {
+		t.Fatalf(`Readlink(bin/tsc) = %q, %v, Wanted a copy of the symlink`, linkname, err)
+	}
+}
+
+func TestListGlobalPackages(t *testing.T) {
+	root, linked := t.TempDir(), t.TempDir()
+	packagesPath, _ := globalPackagePaths()
+	writeTestFile(t, filepath.Join(root, packagesPath, "npm", "package.json"), `{"version": "8.9.0"}`)
+	writeTestFile(t, filepath.Join(root, packagesPath, "corepack", "package.json"), `{"version": "0.10.0"}`)
+	writeTestFile(t, filepath.Join(root, packagesPath, "typescript", "package.json"), `{"name": "typescript", "version": "5.4.5"}`)
+	writeTestFile(t, filepath.Join(root, packagesPath, "@angular", "cli", "package.json"), `{"version": "17.3.0"}`)
+	writeTestFile(t, filepath.Join(root, packagesPath, ".package-lock.json"), "{}")
+	writeTestFile(t, filepath.Join(linked, "package.json"), `{"version": "1.0.0"}`)
+	if err := os.Symlink(linked, filepath.Join(root, packagesPath, "local-tool")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	linked, _ = filepath.EvalSymlinks(linked)
+
+	packages, err := ListGlobalPackages(root)
+	wanted := []GlobalPackage{{"@angular/cli", ""}, {"local-tool", linked}, {"typescript", ""}}
+	if err != nil || !reflect.DeepEqual(packages, wanted) {
+		t.Fatalf(`ListGlobalPackages() = %v, %v, Wanted = %v`, packages, err, wanted)
+	}
+}
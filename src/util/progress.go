@@ -0,0 +1,261 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProgressDownload = "download"
+	ProgressExtract  = "extract"
+)
+
+// ProgressEvent describes how far a download or extraction has progressed.
+type ProgressEvent struct {
+	// Type is ProgressDownload or ProgressExtract.
+	Type string `json:"type"`
+	// Name of the file being downloaded or extracted.
+	Name string `json:"name"`
+	// Bytes downloaded, or bytes of the archive read while extracting.
+	Bytes int64 `json:"bytes"`
+	// Total bytes expected, -1 when unknown.
+	Total int64 `json:"total"`
+	// Rate in bytes per second.
+	Rate float64 `json:"rate"`
+	// ETA in seconds, -1 when unknown.
+	ETA float64 `json:"eta"`
+	// Files extracted so far, only set for ProgressExtract.
+	Files int `json:"files,omitempty"`
+	// Done is set on the last event of a download or extraction.
+	Done bool `json:"done"`
+}
+
+// Reporter receives progress and status messages from long running operations.
+type Reporter interface {
+	Progress(event ProgressEvent)
+	Message(message string)
+}
+
+// NopReporter discards progress, but still prints messages to stdout.
+type NopReporter struct{}
+
+func (NopReporter) Progress(event ProgressEvent) {}
+
+func (NopReporter) Message(message string) {
+	fmt.Println(message)
+}
+
+// NewTextReporter prints messages to out and progress to progressOut. When progressOut is a terminal, progress is
+// rendered as a progress bar, otherwise a line is printed every interval.
+func NewTextReporter(out io.Writer, progressOut io.Writer, interval time.Duration) Reporter {
+	return &textReporter{out: out, progressOut: progressOut, tty: isTerminal(progressOut), interval: interval}
+}
+
+// NewJsonReporter writes progress and messages to out as JSON lines.
+func NewJsonReporter(out io.Writer) Reporter {
+	return &jsonReporter{encoder: json.NewEncoder(out)}
+}
+
+type textReporter struct {
+	mutex       sync.Mutex
+	out         io.Writer
+	progressOut io.Writer
+	tty         bool
+	interval    time.Duration
+	lastLine    time.Time
+	linePrinted bool
+	barVisible  bool
+}
+
+func (r *textReporter) Progress(event ProgressEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.tty {
+		fmt.Fprint(r.progressOut, "\r\033[K"+formatProgress(event, true))
+		r.barVisible = !event.Done
+		if event.Done {
+			fmt.Fprintln(r.progressOut)
+		}
+		return
+	}
+
+	// Only operations that take longer than the interval are reported, to keep logs of fast installs short.
+	now := time.Now()
+	if r.lastLine.IsZero() {
+		r.lastLine = now
+	}
+	if (!event.Done && now.Sub(r.lastLine) >= r.interval) || (event.Done && r.linePrinted) {
+		r.lastLine = now
+		r.linePrinted = true
+		fmt.Fprintln(r.progressOut, formatProgress(event, false))
+	}
+	if event.Done {
+		r.lastLine = time.Time{}
+		r.linePrinted = false
+	}
+}
+
+func (r *textReporter) Message(message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.barVisible {
+		fmt.Fprint(r.progressOut, "\r\033[K")
+		r.barVisible = false
+	}
+	fmt.Fprintln(r.out, message)
+}
+
+type jsonReporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+func (r *jsonReporter) Progress(event ProgressEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_ = r.encoder.Encode(event)
+}
+
+func (r *jsonReporter) Message(message string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_ = r.encoder.Encode(struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}{"message", message})
+}
+
+func formatProgress(event ProgressEvent, bar bool) string {
+	verb := "downloading"
+	if event.Type == ProgressExtract {
+		verb = "extracting"
+	}
+
+	builder := new(strings.Builder)
+	builder.WriteString(verb + " " + event.Name + " ")
+	if event.Total > 0 {
+		percent := float64(event.Bytes) / float64(event.Total)
+		if bar {
+			width := 30
+			filled := int(percent * float64(width))
+			builder.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "] ")
+		}
+		builder.WriteString(fmt.Sprintf("%3.0f%% %s/%s", percent*100, formatBytes(event.Bytes), formatBytes(event.Total)))
+	} else {
+		builder.WriteString(formatBytes(event.Bytes))
+	}
+	if event.Type == ProgressExtract {
+		builder.WriteString(fmt.Sprintf(", %d files", event.Files))
+	}
+	if event.Done {
+		builder.WriteString(", done")
+	} else {
+		builder.WriteString(", " + formatBytes(int64(event.Rate)) + "/s")
+		if event.ETA >= 0 {
+			builder.WriteString(", " + (time.Duration(event.ETA) * time.Second).String() + " left")
+		}
+	}
+
+	return builder.String()
+}
+
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/1024/1024)
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressTracker computes the rate and ETA of an operation and sends throttled events to a Reporter.
+type progressTracker struct {
+	reporter   Reporter
+	event      ProgressEvent
+	start      time.Time
+	startBytes int64
+	lastReport time.Time
+}
+
+const progressReportInterval = 200 * time.Millisecond
+
+func newProgressTracker(reporter Reporter, eventType string, name string, startBytes int64, total int64) *progressTracker {
+	if reporter == nil {
+		reporter = NopReporter{}
+	}
+	return &progressTracker{
+		reporter:   reporter,
+		event:      ProgressEvent{Type: eventType, Name: name, Bytes: startBytes, Total: total, ETA: -1},
+		start:      time.Now(),
+		startBytes: startBytes,
+	}
+}
+
+func (t *progressTracker) add(bytes int64, files int) {
+	t.event.Bytes += bytes
+	t.event.Files += files
+
+	now := time.Now()
+	if now.Sub(t.lastReport) < progressReportInterval {
+		return
+	}
+	t.lastReport = now
+	t.report(now)
+}
+
+func (t *progressTracker) done() {
+	t.event.Done = true
+	t.report(time.Now())
+}
+
+func (t *progressTracker) report(now time.Time) {
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		t.event.Rate = float64(t.event.Bytes-t.startBytes) / elapsed
+	}
+	if t.event.Total > 0 && t.event.Rate > 0 {
+		t.event.ETA = float64(t.event.Total-t.event.Bytes) / t.event.Rate
+	}
+	t.reporter.Progress(t.event)
+}
+
+// progressWriter reports every write to a progressTracker.
+type progressWriter struct {
+	w       io.Writer
+	tracker *progressTracker
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.tracker.add(int64(n), 0)
+	return n, err
+}
+
+// progressReader reports every read to a progressTracker.
+type progressReader struct {
+	r       io.Reader
+	tracker *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.tracker.add(int64(n), 0)
+	return n, err
+}
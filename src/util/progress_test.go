@@ -0,0 +1,62 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextReporterSkipsFastOperationsWhenNotATerminal(t *testing.T) {
+	out := new(bytes.Buffer)
+	progressOut := new(bytes.Buffer)
+	reporter := NewTextReporter(out, progressOut, time.Hour)
+
+	reporter.Progress(ProgressEvent{Type: ProgressDownload, Name: "node.tar.gz", Bytes: 10, Total: 20})
+	reporter.Progress(ProgressEvent{Type: ProgressDownload, Name: "node.tar.gz", Bytes: 20, Total: 20, Done: true})
+	reporter.Message("successfully installed v18.2.0")
+
+	if progressOut.Len() != 0 {
+		t.Fatalf(`progress = %q, Wanted no progress`, progressOut.String())
+	}
+	if out.String() != "successfully installed v18.2.0\n" {
+		t.Fatalf(`messages = %q, Wanted = %q`, out.String(), "successfully installed v18.2.0\n")
+	}
+}
+
+func TestTextReporterPrintsPeriodicLinesWhenNotATerminal(t *testing.T) {
+	progressOut := new(bytes.Buffer)
+	reporter := NewTextReporter(new(bytes.Buffer), progressOut, time.Nanosecond)
+
+	reporter.Progress(ProgressEvent{Type: ProgressDownload, Name: "node.tar.gz", Bytes: 0, Total: 20})
+	time.Sleep(time.Millisecond)
+	reporter.Progress(ProgressEvent{Type: ProgressDownload, Name: "node.tar.gz", Bytes: 10, Total: 20, Rate: 10, ETA: 1})
+	reporter.Progress(ProgressEvent{Type: ProgressDownload, Name: "node.tar.gz", Bytes: 20, Total: 20, Done: true})
+
+	lines := strings.Split(strings.TrimSpace(progressOut.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], " 50% 10 B/20 B") || !strings.HasSuffix(lines[1], "done") {
+		t.Fatalf(`progress = %q, Wanted a 50%% line and a done line`, progressOut.String())
+	}
+}
+
+func TestJsonReporter(t *testing.T) {
+	out := new(bytes.Buffer)
+	reporter := NewJsonReporter(out)
+
+	reporter.Progress(ProgressEvent{Type: ProgressExtract, Name: "node.tar.gz", Bytes: 5, Total: 10, Files: 3})
+	reporter.Message("now using node v18.2.0")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf(`output = %q, Wanted 2 lines`, out.String())
+	}
+	event := ProgressEvent{}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil || event.Type != ProgressExtract || event.Files != 3 {
+		t.Fatalf(`event = %+v, %v, Wanted an extract event with 3 files`, event, err)
+	}
+	message := map[string]string{}
+	if err := json.Unmarshal([]byte(lines[1]), &message); err != nil || message["type"] != "message" || message["message"] != "now using node v18.2.0" {
+		t.Fatalf(`message = %v, %v, Wanted a message event`, message, err)
+	}
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"github.com/Masterminds/semver/v3"
+	"strings"
+)
+
+// IsExactVersion reports whether expression is a complete version (major.minor.patch) that does not need resolving.
+func IsExactVersion(expression string) bool {
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(strings.ToLower(expression), "v"))
+	return err == nil
+}
+
+// IsLtsAlias reports whether expression is one of the lts keywords (lts, lts/*, lts/<codename>).
+func IsLtsAlias(expression string) bool {
+	expression = strings.ToLower(expression)
+	return expression == "lts" || strings.HasPrefix(expression, "lts/")
+}
+
+// ResolveVersion returns the newest version from entries that satisfies expression.
+// entries must be sorted from oldest to newest, as returned by ParseIndex.
+//
+// expression can be an exact version (18.2.0), a partial version (18, 18.2), a semver range (^18.2, >=20 <22),
+// or one of the keywords latest, node, lts, lts/* and lts/<codename>.
+func ResolveVersion(expression string, entries []IndexEntry) (string, error) {
+	if len(expression) == 0 {
+		return "", errors.New("version is required")
+	}
+	lowerExpression := strings.ToLower(strings.TrimSpace(expression))
+
+	var matches func(entry IndexEntry, version *semver.Version) bool
+	switch {
+	case lowerExpression == "latest" || lowerExpression == "node":
+		matches = func(entry IndexEntry, version *semver.Version) bool {
+			return version.Prerelease() == ""
+		}
+	case lowerExpression == "lts" || lowerExpression == "lts/*":
+		matches = func(entry IndexEntry, version *semver.Version) bool {
+			return entry.Lts != ""
+		}
+	case strings.HasPrefix(lowerExpression, "lts/"):
+		codename := strings.TrimPrefix(lowerExpression, "lts/")
+		matches = func(entry IndexEntry, version *semver.Version) bool {
+			return strings.ToLower(string(entry.Lts)) == codename
+		}
+	case IsExactVersion(lowerExpression):
+		exactVersion := semver.MustParse(lowerExpression)
+		matches = func(entry IndexEntry, version *semver.Version) bool {
+			return version.Equal(exactVersion)
+		}
+	default:
+		constraint, err := semver.NewConstraint(lowerExpression)
+		if err != nil {
+			return "", errors.New("unable to parse version " + expression + ": " + err.Error())
+		}
+		matches = func(entry IndexEntry, version *semver.Version) bool {
+			return constraint.Check(version)
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		version, err := semver.NewVersion(entries[i].Version)
+		if err != nil {
+			continue
+		}
+		if matches(entries[i], version) {
+			return "v" + version.String(), nil
+		}
+	}
+
+	return "", errors.New("no version matching " + expression + " was found")
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"testing"
+)
+
+var resolveEntries = []IndexEntry{
+	{Version: "v16.20.2", Lts: "Gallium"},
+	{Version: "v18.2.0"},
+	{Version: "v18.20.4", Lts: "Hydrogen"},
+	{Version: "v20.11.0", Lts: "Iron"},
+	{Version: "v21.7.3"},
+	{Version: "v22.0.0-rc.1"},
+}
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"18.2.0", "v18.2.0"},
+		{"V18.2.0", "v18.2.0"},
+		{"18", "v18.20.4"},
+		{"18.2", "v18.2.0"},
+		{"^18.2", "v18.20.4"},
+		{">=20 <22", "v21.7.3"},
+		{"latest", "v21.7.3"},
+		{"node", "v21.7.3"},
+		{"lts", "v20.11.0"},
+		{"lts/*", "v20.11.0"},
+		{"lts/hydrogen", "v18.20.4"},
+		{"LTS/Gallium", "v16.20.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			version, err := ResolveVersion(tt.expression, resolveEntries)
+			if err != nil || version != tt.expected {
+				t.Fatalf(`ResolveVersion(%q) = %q, %v, Wanted = %q`, tt.expression, version, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveVersionErrors(t *testing.T) {
+	for _, expression := range []string{"", "19", "18.3.0", "lts/argon", "not a version"} {
+		t.Run(expression, func(t *testing.T) {
+			if version, err := ResolveVersion(expression, resolveEntries); err == nil {
+				t.Fatalf(`ResolveVersion(%q) = %q, nil, Wanted an error`, expression, version)
+			}
+		})
+	}
+}
+
+func TestIsExactVersion(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{"18.2.0", true},
+		{"v18.2.0", true},
+		{"18.2", false},
+		{"^18.2.0", false},
+		{"lts", false},
+	}
+
+	for _, tt := range tests {
+		if actual := IsExactVersion(tt.expression); actual != tt.expected {
+			t.Fatalf(`IsExactVersion(%q) = %v, Wanted = %v`, tt.expression, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+type Shell string
+
+const (
+	ShellBash       Shell = "bash"
+	ShellZsh        Shell = "zsh"
+	ShellFish       Shell = "fish"
+	ShellPowerShell Shell = "powershell"
+)
+
+// Environment variables set in a shell activated by nvmc shell.
+const (
+	ShellPathEnv    = "NVMC_SHELL_PATH"
+	ShellVersionEnv = "NVMC_SHELL_VERSION"
+	ShellSourceEnv  = "NVMC_SHELL_SOURCE"
+)
+
+// ShellActivation is the state of a shell after running the code generated by ShellScript.
+type ShellActivation struct {
+	// Path is the new PATH of the shell.
+	Path string
+	// BinPath is the version's bin directory prepended to PATH, empty when deactivating.
+	BinPath string
+	// Version that is active in the shell, empty when deactivating.
+	Version string
+	// Source is the version file that supplied the version, empty when the version was given explicitly.
+	Source string
+}
+
+func ParseShell(name string) (Shell, error) {
+	switch strings.ToLower(name) {
+	case "bash":
+		return ShellBash, nil
+	case "zsh":
+		return ShellZsh, nil
+	case "fish":
+		return ShellFish, nil
+	case "powershell", "pwsh":
+		return ShellPowerShell, nil
+	default:
+		return "", errors.New("unsupported shell " + name + ", must be one of bash, zsh, fish, powershell")
+	}
+}
+
+// DetectShell guesses the shell from the SHELL environment variable, PowerShell is assumed on Windows.
+func DetectShell() (Shell, error) {
+	if shell := os.Getenv("SHELL"); len(shell) > 0 {
+		return ParseShell(strings.TrimSuffix(filepath.Base(shell), ".exe"))
+	}
+	if runtime.GOOS == "windows" {
+		return ShellPowerShell, nil
+	}
+	return "", errors.New("unable to detect the shell, specify it with --shell")
+}
+
+// ShellPath returns path with previousBinPath removed and binPath prepended.
+// An empty binPath only removes previousBinPath.
+func ShellPath(path string, binPath string, previousBinPath string) string {
+	entries := make([]string, 0)
+	if len(binPath) > 0 {
+		entries = append(entries, binPath)
+	}
+	for _, entry := range filepath.SplitList(path) {
+		if len(entry) == 0 || entry == binPath || (len(previousBinPath) > 0 && entry == previousBinPath) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return strings.Join(entries, string(os.PathListSeparator))
+}
+
+// ShellScript returns the code that applies activation to the current shell.
+func ShellScript(shell Shell, activation ShellActivation) string {
+	variables := [][2]string{
+		{ShellPathEnv, activation.BinPath},
+		{ShellVersionEnv, activation.Version},
+		{ShellSourceEnv, activation.Source},
+	}
+
+	builder := new(strings.Builder)
+	switch shell {
+	case ShellFish:
+		builder.WriteString("set -gx PATH")
+		for _, entry := range filepath.SplitList(activation.Path) {
+			builder.WriteString(" " + fishQuote(entry))
+		}
+		builder.WriteString("\n")
+		for _, variable := range variables {
+			if len(variable[1]) > 0 {
+				builder.WriteString("set -gx " + variable[0] + " " + fishQuote(variable[1]) + "\n")
+			} else {
+				builder.WriteString("set -e " + variable[0] + "\n")
+			}
+		}
+	case ShellPowerShell:
+		builder.WriteString("$env:PATH = " + powerShellQuote(activation.Path) + "\n")
+		for _, variable := range variables {
+			if len(variable[1]) > 0 {
+				builder.WriteString("$env:" + variable[0] + " = " + powerShellQuote(variable[1]) + "\n")
+			} else {
+				builder.WriteString("Remove-Item -Path Env:" + variable[0] + " -ErrorAction SilentlyContinue\n")
+			}
+		}
+	default:
+		builder.WriteString("export PATH=" + posixQuote(activation.Path) + "\n")
+		for _, variable := range variables {
+			if len(variable[1]) > 0 {
+				builder.WriteString("export " + variable[0] + "=" + posixQuote(variable[1]) + "\n")
+			} else {
+				builder.WriteString("unset " + variable[0] + "\n")
+			}
+		}
+		builder.WriteString("hash -r\n")
+	}
+
+	return builder.String()
+}
+
+// ShellHook returns the code to add to a shell's startup file. It defines an nvmc wrapper so that
+// nvmc shell <version> changes the current shell, and when useOnCd is set, switches versions when
+// the working directory changes to a directory with a version file.
+func ShellHook(shell Shell, useOnCd bool) string {
+	switch shell {
+	case ShellFish:
+		hook := `function nvmc
+  if test (count $argv) -gt 0; and test "$argv[1]" = shell
+    command nvmc shell --shell fish $argv[2..-1] | source
+  else
+    command nvmc $argv
+  end
+end
+`
+		if useOnCd {
+			hook += `function __nvmc_use_on_cd --on-variable PWD
+  command nvmc shell --shell fish --auto | source
+end
+__nvmc_use_on_cd
+`
+		}
+		return hook
+	case ShellPowerShell:
+		hook := `function global:nvmc {
+  $nvmcCommand = Get-Command nvmc -CommandType Application | Select-Object -First 1
+  if ($args.Count -gt 0 -and $args[0] -eq 'shell') {
+    & $nvmcCommand shell --shell powershell @($args | Select-Object -Skip 1) | Out-String | Invoke-Expression
+  } else {
+    & $nvmcCommand @args
+  }
+}
+`
+		if useOnCd {
+			hook += `$global:__nvmcLastPwd = $null
+$global:__nvmcPrompt = $function:prompt
+function global:prompt {
+  if ($global:__nvmcLastPwd -ne $PWD.Path) {
+    $global:__nvmcLastPwd = $PWD.Path
+    $nvmcCommand = Get-Command nvmc -CommandType Application | Select-Object -First 1
+    & $nvmcCommand shell --shell powershell --auto | Out-String | Invoke-Expression
+  }
+  & $global:__nvmcPrompt
+}
+`
+		}
+		return hook
+	case ShellZsh:
+		hook := `nvmc() {
+  if [ "$1" = "shell" ]; then
+    shift
+    eval "$(command nvmc shell --shell zsh "$@")"
+  else
+    command nvmc "$@"
+  fi
+}
+`
+		if useOnCd {
+			hook += `autoload -U add-zsh-hook
+__nvmc_use_on_cd() {
+  eval "$(command nvmc shell --shell zsh --auto)"
+}
+add-zsh-hook chpwd __nvmc_use_on_cd
+__nvmc_use_on_cd
+`
+		}
+		return hook
+	default:
+		hook := `nvmc() {
+  if [ "$1" = "shell" ]; then
+    shift
+    eval "$(command nvmc shell --shell bash "$@")"
+  else
+    command nvmc "$@"
+  fi
+}
+`
+		if useOnCd {
+			hook += `__nvmc_use_on_cd() {
+  if [ "$__nvmc_last_pwd" != "$PWD" ]; then
+    __nvmc_last_pwd="$PWD"
+    eval "$(command nvmc shell --shell bash --auto)"
+  fi
+}
+PROMPT_COMMAND="__nvmc_use_on_cd${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+`
+		}
+		return hook
+	}
+}
+
+func posixQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func fishQuote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+func powerShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestShellPath(t *testing.T) {
+	separator := string(os.PathListSeparator)
+	path := strings.Join([]string{"/old/bin", "/usr/bin", "/bin"}, separator)
+
+	actual := ShellPath(path, "/new/bin", "/old/bin")
+	expected := strings.Join([]string{"/new/bin", "/usr/bin", "/bin"}, separator)
+	if actual != expected {
+		t.Fatalf(`ShellPath() = %q, Wanted = %q`, actual, expected)
+	}
+
+	actual = ShellPath(path, "", "/old/bin")
+	expected = strings.Join([]string{"/usr/bin", "/bin"}, separator)
+	if actual != expected {
+		t.Fatalf(`ShellPath() = %q, Wanted = %q`, actual, expected)
+	}
+}
+
+func TestShellScriptQuotes(t *testing.T) {
+	activation := ShellActivation{Path: "/it's/bin", BinPath: "/it's/bin", Version: "v18.2.0"}
+	tests := []struct {
+		shell    Shell
+		expected []string
+	}{
+		{ShellBash, []string{`export PATH='/it'\''s/bin'`, `export NVMC_SHELL_VERSION='v18.2.0'`, "unset NVMC_SHELL_SOURCE"}},
+		{ShellFish, []string{`set -gx NVMC_SHELL_PATH '/it\'s/bin'`, "set -e NVMC_SHELL_SOURCE"}},
+		{ShellPowerShell, []string{`$env:NVMC_SHELL_PATH = '/it''s/bin'`, "Remove-Item -Path Env:NVMC_SHELL_SOURCE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.shell), func(t *testing.T) {
+			script := ShellScript(tt.shell, activation)
+			for _, expected := range tt.expected {
+				if !strings.Contains(script, expected) {
+					t.Fatalf("ShellScript() = %s, Wanted to contain %s", script, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestParseShell(t *testing.T) {
+	if shell, err := ParseShell("pwsh"); err != nil || shell != ShellPowerShell {
+		t.Fatalf(`ParseShell("pwsh") = %q, %v, Wanted = %q`, shell, err, ShellPowerShell)
+	}
+	if _, err := ParseShell("tcsh"); err == nil {
+		t.Fatalf(`ParseShell("tcsh") error = nil, Wanted an error`)
+	}
+}
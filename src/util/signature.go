@@ -0,0 +1,94 @@
+package util
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"os"
+)
+
+//go:generate go run ./keys/generate -fingerprints keys/fingerprints.txt -out keys/nodejs.asc
+
+// nodejsKeyring is the armored public keys of the Node.js release team. keys/nodejs.asc is committed, run go generate
+// to refresh it after keys/fingerprints.txt changes. TestBundledKeyring fails when it does not match the fingerprints.
+//
+//go:embed keys/nodejs.asc
+var nodejsKeyring []byte
+
+type SignaturePolicy string
+
+const (
+	// SignatureRequired fails when SHASUMS256.txt is not signed or there are no keys to verify it with.
+	SignatureRequired SignaturePolicy = "required"
+	// SignatureOptional verifies the signature when there is one and there are keys to verify it with.
+	SignatureOptional SignaturePolicy = "optional"
+	SignatureOff      SignaturePolicy = "off"
+)
+
+func ParseSignaturePolicy(policy string) (SignaturePolicy, error) {
+	switch SignaturePolicy(policy) {
+	case SignatureRequired, SignatureOptional, SignatureOff:
+		return SignaturePolicy(policy), nil
+	default:
+		return "", errors.New("invalid signature policy " + policy + ", must be one of required, optional, off")
+	}
+}
+
+// LoadKeyring returns the bundled Node.js release keys, plus the keys in keyringPath when it is set.
+// keyringPath can be an armored or binary keyring.
+func LoadKeyring(keyringPath string) (openpgp.EntityList, error) {
+	keyring := openpgp.EntityList{}
+	if len(bytes.TrimSpace(nodejsKeyring)) > 0 {
+		bundled, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(nodejsKeyring))
+		if err != nil {
+			return nil, errors.New("unable to read the bundled keyring: " + err.Error())
+		}
+		keyring = append(keyring, bundled...)
+	}
+
+	if len(keyringPath) > 0 {
+		contents, err := os.ReadFile(keyringPath)
+		if err != nil {
+			return nil, err
+		}
+		var keys openpgp.EntityList
+		if isArmored(contents) {
+			keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(contents))
+		} else {
+			keys, err = openpgp.ReadKeyRing(bytes.NewReader(contents))
+		}
+		if err != nil {
+			return nil, errors.New("unable to read keyring " + keyringPath + ": " + err.Error())
+		}
+		keyring = append(keyring, keys...)
+	}
+
+	return keyring, nil
+}
+
+// VerifyDetachedSignature checks signature, the contents of SHASUMS256.txt.sig, against sums.
+func VerifyDetachedSignature(sums []byte, signature []byte, keyring openpgp.EntityList) (*openpgp.Entity, error) {
+	if isArmored(signature) {
+		return openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(signature), nil)
+	}
+	return openpgp.CheckDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(signature), nil)
+}
+
+// VerifyClearSigned checks the clear signed contents of SHASUMS256.txt.asc, returning the signed sums.
+func VerifyClearSigned(data []byte, keyring openpgp.EntityList) ([]byte, *openpgp.Entity, error) {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("not a clear signed message")
+	}
+	signer, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block.Plaintext, signer, nil
+}
+
+func isArmored(contents []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(contents), []byte("-----BEGIN PGP"))
+}
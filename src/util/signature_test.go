@@ -0,0 +1,134 @@
+package util
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var signatureTestSums = []byte("39a201466504ca912817459ac28725ae346cfa61a5639d3afedc61768a617cf6  node-v18.2.0-linux-x64.tar.gz\n")
+
+func newTestEntity(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Release Tester", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	return entity
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	signer := newTestEntity(t)
+	signature := new(bytes.Buffer)
+	if err := openpgp.DetachSign(signature, signer, bytes.NewReader(signatureTestSums), nil); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	if _, err := VerifyDetachedSignature(signatureTestSums, signature.Bytes(), openpgp.EntityList{signer}); err != nil {
+		t.Fatalf(`VerifyDetachedSignature() error = %v`, err)
+	}
+
+	tampered := bytes.Replace(signatureTestSums, []byte("39a2"), []byte("0000"), 1)
+	if _, err := VerifyDetachedSignature(tampered, signature.Bytes(), openpgp.EntityList{signer}); err == nil {
+		t.Fatalf(`VerifyDetachedSignature() of tampered sums error = nil, Wanted an error`)
+	}
+	if _, err := VerifyDetachedSignature(signatureTestSums, signature.Bytes(), openpgp.EntityList{newTestEntity(t)}); err == nil {
+		t.Fatalf(`VerifyDetachedSignature() with an untrusted key error = nil, Wanted an error`)
+	}
+}
+
+func TestVerifyClearSigned(t *testing.T) {
+	signer := newTestEntity(t)
+	clearSigned := new(bytes.Buffer)
+	writer, err := clearsign.Encode(clearSigned, signer.PrivateKey, nil)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if _, err := writer.Write(signatureTestSums); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	sums, _, err := VerifyClearSigned(clearSigned.Bytes(), openpgp.EntityList{signer})
+	if err != nil || !bytes.Equal(bytes.TrimSpace(sums), bytes.TrimSpace(signatureTestSums)) {
+		t.Fatalf(`VerifyClearSigned() = %q, %v, Wanted = %q`, sums, err, signatureTestSums)
+	}
+	if _, _, err := VerifyClearSigned(signatureTestSums, openpgp.EntityList{signer}); err == nil {
+		t.Fatalf(`VerifyClearSigned() of an unsigned file error = nil, Wanted an error`)
+	}
+}
+
+func TestLoadKeyringReadsCustomKeyring(t *testing.T) {
+	signer := newTestEntity(t)
+	keyring := new(bytes.Buffer)
+	writer, err := armor.Encode(keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("Failed to armor: %v", err)
+	}
+	if err := signer.Serialize(writer); err != nil {
+		t.Fatalf("Failed to serialize key: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to armor: %v", err)
+	}
+	keyringPath := filepath.Join(t.TempDir(), "keyring.asc")
+	writeTestFile(t, keyringPath, keyring.String())
+
+	bundled, err := LoadKeyring("")
+	if err != nil {
+		t.Fatalf(`LoadKeyring("") error = %v`, err)
+	}
+	keys, err := LoadKeyring(keyringPath)
+	if err != nil || len(keys) != len(bundled)+1 {
+		t.Fatalf(`LoadKeyring() = %d keys, %v, Wanted = %d keys`, len(keys), err, len(bundled)+1)
+	}
+}
+
+// TestBundledKeyring fails when nvmc would be built without the Node.js release keys, which would silently disable
+// signature verification with the default --verify-signature=optional.
+func TestBundledKeyring(t *testing.T) {
+	contents, err := os.ReadFile(filepath.Join("keys", "fingerprints.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read the fingerprints: %v", err)
+	}
+	wanted := make([]string, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 && !strings.HasPrefix(line, "#") {
+			fingerprint, _, _ := strings.Cut(line, " ")
+			wanted = append(wanted, strings.ToUpper(fingerprint))
+		}
+	}
+
+	keyring, err := LoadKeyring("")
+	if err != nil || len(keyring) == 0 {
+		t.Fatalf(`LoadKeyring("") = %d keys, %v, Wanted the keys of keys/fingerprints.txt, run go generate ./util and commit keys/nodejs.asc`, len(keyring), err)
+	}
+	actual := make([]string, 0, len(keyring))
+	for _, entity := range keyring {
+		actual = append(actual, strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)))
+	}
+	slices.Sort(actual)
+	slices.Sort(wanted)
+	if !slices.Equal(actual, wanted) {
+		t.Fatalf(`LoadKeyring("") = %v, Wanted = %v, run go generate ./util and commit keys/nodejs.asc`, actual, wanted)
+	}
+}
+
+func TestParseSignaturePolicy(t *testing.T) {
+	if policy, err := ParseSignaturePolicy("required"); err != nil || policy != SignatureRequired {
+		t.Fatalf(`ParseSignaturePolicy("required") = %q, %v, Wanted = %q`, policy, err, SignatureRequired)
+	}
+	if _, err := ParseSignaturePolicy("sometimes"); err == nil {
+		t.Fatalf(`ParseSignaturePolicy("sometimes") error = nil, Wanted an error`)
+	}
+}
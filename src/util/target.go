@@ -0,0 +1,123 @@
+package util
+
+import (
+	"errors"
+	"strings"
+)
+
+// LibcMusl is the C library of the builds for Alpine Linux and other musl distributions.
+const LibcMusl = "musl"
+
+// UnofficialBuildsUrl is the download URL of the builds that nodejs.org does not publish, e.g. for musl.
+const UnofficialBuildsUrl = "https://unofficial-builds.nodejs.org/download/release"
+
+// Target is the platform, arch and C library that node is built for, using the names of the node archives.
+type Target struct {
+	Platform string
+	Arch     string
+	// Libc is empty for the default C library of the platform, or LibcMusl.
+	Libc string
+}
+
+// NativeTarget returns the target of the current os and arch.
+func NativeTarget() Target {
+	return Target{getNodeOs(), getNodeArch(), ""}
+}
+
+// ParseTarget returns the target for the --platform, --arch and --libc options. Empty options default to the
+// current os and arch. The names of Go, e.g. windows and amd64, are accepted too.
+func ParseTarget(platform string, arch string, libc string) (Target, error) {
+	target := NativeTarget()
+
+	switch strings.ToLower(platform) {
+	case "":
+	case "linux", "aix", "sunos":
+		target.Platform = strings.ToLower(platform)
+	case "darwin", "macos", "osx":
+		target.Platform = "darwin"
+	case "win", "windows":
+		target.Platform = "win"
+	default:
+		return Target{}, errors.New("invalid platform " + platform + ", must be one of linux, darwin, win, aix, sunos")
+	}
+
+	switch strings.ToLower(arch) {
+	case "":
+	case "x64", "amd64":
+		target.Arch = "x64"
+	case "x86", "386", "ia32":
+		target.Arch = "x86"
+	case "arm64", "aarch64":
+		target.Arch = "arm64"
+	case "armv7l", "arm":
+		target.Arch = "armv7l"
+	case "ppc64le", "ppc64", "s390x", "riscv64", "loong64":
+		target.Arch = strings.ToLower(arch)
+	default:
+		return Target{}, errors.New("invalid arch " + arch + ", must be one of x64, x86, arm64, armv7l, ppc64le, ppc64, s390x, riscv64, loong64")
+	}
+
+	switch strings.ToLower(libc) {
+	case "", "glibc":
+	case LibcMusl:
+		if target.Platform != "linux" {
+			return Target{}, errors.New("invalid libc " + libc + ", musl builds are only published for linux")
+		}
+		target.Libc = LibcMusl
+	default:
+		return Target{}, errors.New("invalid libc " + libc + ", must be one of glibc, musl")
+	}
+
+	return target, nil
+}
+
+// ParseTargetName returns the target for its name in the names of node archives, e.g. linux-x64 or linux-x64-musl.
+func ParseTargetName(name string) (Target, error) {
+	parts := strings.Split(name, "-")
+	if len(parts) == 2 {
+		parts = append(parts, "")
+	}
+	if len(parts) != 3 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return Target{}, errors.New("invalid platform " + name + ", expected a platform and arch like linux-x64 or linux-x64-musl")
+	}
+	return ParseTarget(parts[0], parts[1], parts[2])
+}
+
+// String returns the target as it appears in the names of node archives, e.g. linux-x64-musl.
+func (t Target) String() string {
+	if len(t.Libc) > 0 {
+		return t.Platform + "-" + t.Arch + "-" + t.Libc
+	}
+	return t.Platform + "-" + t.Arch
+}
+
+// IsNative returns whether the target is the current os and arch.
+func (t Target) IsNative() bool {
+	return t == NativeTarget()
+}
+
+// IsUnofficial returns whether the builds of the target are only published to UnofficialBuildsUrl.
+func (t Target) IsUnofficial() bool {
+	return t.Libc == LibcMusl || (t.Platform == "linux" && (t.Arch == "x86" || t.Arch == "riscv64" || t.Arch == "loong64"))
+}
+
+// indexFile returns the name of the archive of the target in the files list of the index.
+func (t Target) indexFile() string {
+	switch t.Platform {
+	case "darwin":
+		return "osx-" + t.Arch + "-tar"
+	case "win":
+		return "win-" + t.Arch + "-zip"
+	default:
+		return t.String()
+	}
+}
+
+// InstallName returns the name of the directory that version is installed to for target. The native target uses
+// the version, other targets add the target, e.g. v18.2.0-linux-x64-musl, so that they are installed side by side.
+func InstallName(version string, target Target) string {
+	if target.IsNative() || strings.HasSuffix(version, "-"+target.String()) {
+		return version
+	}
+	return version + "-" + target.String()
+}
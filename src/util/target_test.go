@@ -0,0 +1,66 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		platform string
+		arch     string
+		libc     string
+		wanted   Target
+	}{
+		{"linux", "x64", "", Target{"linux", "x64", ""}},
+		{"windows", "amd64", "", Target{"win", "x64", ""}},
+		{"macos", "aarch64", "", Target{"darwin", "arm64", ""}},
+		{"linux", "arm64", "musl", Target{"linux", "arm64", LibcMusl}},
+		{"linux", "386", "glibc", Target{"linux", "x86", ""}},
+		{"", "", "", NativeTarget()},
+	}
+	for _, test := range tests {
+		actual, err := ParseTarget(test.platform, test.arch, test.libc)
+		if err != nil || actual != test.wanted {
+			t.Fatalf(`ParseTarget(%q, %q, %q) = %v, %v, Wanted = %v`, test.platform, test.arch, test.libc, actual, err, test.wanted)
+		}
+	}
+}
+
+func TestParseTargetInvalid(t *testing.T) {
+	for _, options := range [][3]string{{"plan9", "", ""}, {"", "mips", ""}, {"linux", "", "uclibc"}, {"darwin", "x64", "musl"}} {
+		if actual, err := ParseTarget(options[0], options[1], options[2]); err == nil {
+			t.Fatalf(`ParseTarget(%q, %q, %q) = %v, Wanted an error`, options[0], options[1], options[2], actual)
+		}
+	}
+}
+
+func TestParseTargetName(t *testing.T) {
+	tests := map[string]Target{
+		"linux-x64":      {"linux", "x64", ""},
+		"win-x64":        {"win", "x64", ""},
+		"linux-x64-musl": {"linux", "x64", LibcMusl},
+	}
+	for name, wanted := range tests {
+		if actual, err := ParseTargetName(name); err != nil || actual != wanted {
+			t.Fatalf(`ParseTargetName(%q) = %v, %v, Wanted = %v`, name, actual, err, wanted)
+		}
+	}
+	for _, name := range []string{"linux", "linux-", "-x64", "linux-x64-musl-extra", "plan9-x64"} {
+		if actual, err := ParseTargetName(name); err == nil {
+			t.Fatalf(`ParseTargetName(%q) = %v, Wanted an error`, name, actual)
+		}
+	}
+}
+
+func TestInstallName(t *testing.T) {
+	if actual := InstallName("v18.2.0", NativeTarget()); actual != "v18.2.0" {
+		t.Fatalf(`InstallName(%q, native) = %q, Wanted = %q`, "v18.2.0", actual, "v18.2.0")
+	}
+	musl := Target{"linux", "s390x", LibcMusl}
+	if actual := InstallName("v18.2.0", musl); actual != "v18.2.0-linux-s390x-musl" {
+		t.Fatalf(`InstallName(%q, %v) = %q, Wanted = %q`, "v18.2.0", musl, actual, "v18.2.0-linux-s390x-musl")
+	}
+	if actual := InstallName("v18.2.0-linux-s390x-musl", musl); actual != "v18.2.0-linux-s390x-musl" {
+		t.Fatalf(`InstallName(%q, %v) = %q, Wanted = %q`, "v18.2.0-linux-s390x-musl", musl, actual, "v18.2.0-linux-s390x-musl")
+	}
+}
@@ -3,144 +3,468 @@ package util
 import (
 	"archive/tar"
 	"archive/zip"
-	"bytes"
+	"bufio"
 	"compress/gzip"
 	"errors"
 	"fmt"
+	"github.com/ulikunitz/xz"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-func Unzip(zipFile fs.File, basePath string) (string, error) {
+// ErrUnsafeArchive matches an UnsafeEntryError.
+var ErrUnsafeArchive = errors.New("unsafe archive")
+
+// UnsafeEntryError is returned when an archive entry would be written outside of the extraction root,
+// or is of a type that is never extracted, like a device file.
+type UnsafeEntryError struct {
+	Name   string
+	Reason string
+}
+
+func (e *UnsafeEntryError) Error() string {
+	return "refusing to extract " + e.Name + ", " + e.Reason
+}
+
+func (e *UnsafeEntryError) Is(target error) bool {
+	return target == ErrUnsafeArchive
+}
+
+// Manifest describes the entries extracted from an archive.
+type Manifest struct {
+	// Root is the top-level directory that contains every entry of the archive, or the extraction path when the
+	// archive does not have a single top-level directory.
+	Root string
+	// Files are the paths of the extracted files, symlinks and hardlinks relative to Root, separated by /.
+	Files []string
+}
+
+// Unzip extracts zipFile into basePath, reporting progress to reporter when it is not nil.
+// Entries that would be written outside of basePath fail with an UnsafeEntryError.
+// Zip archives are read in place, so zipFile must implement io.ReaderAt, like *os.File does.
+func Unzip(zipFile fs.File, basePath string, reporter Reporter) (*Manifest, error) {
 	fileInfo, err := zipFile.Stat()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if strings.HasSuffix(fileInfo.Name(), ".zip") {
-		return zipUnzip(zipFile, basePath)
+	tracker := newProgressTracker(reporter, ProgressExtract, fileInfo.Name(), 0, fileInfo.Size())
+
+	var manifest *Manifest
+	if strings.HasSuffix(fileInfo.Name(), string(ArchiveZip)) {
+		readerAt, ok := zipFile.(io.ReaderAt)
+		if !ok {
+			return nil, errors.New("unable to read " + fileInfo.Name() + ", zip archives must be read from a file")
+		}
+		manifest, err = zipUnzip(readerAt, fileInfo.Size(), basePath, tracker)
 	} else {
-		return tarGzUnzip(zipFile, basePath)
+		manifest, err = unzipTar(&progressReader{zipFile, tracker}, fileInfo.Name(), basePath, tracker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tracker.done()
+
+	return manifest, nil
+}
+
+// UnzipStream extracts the tar.gz or tar.xz archive name from reader into basePath as it is read, e.g. while it is
+// downloaded. Zip archives can not be extracted from a stream, their list of files is at the end.
+func UnzipStream(reader io.Reader, name string, basePath string) (*Manifest, error) {
+	if strings.HasSuffix(name, string(ArchiveZip)) {
+		return nil, errors.New("unable to extract " + name + " from a stream, zip archives must be read from a file")
 	}
+	return unzipTar(reader, name, basePath, newProgressTracker(nil, ProgressExtract, name, 0, 0))
 }
 
-func tarGzUnzip(zipFile fs.File, basePath string) (string, error) {
-	zipReadCloser, err := gzip.NewReader(zipFile)
+// unzipTar extracts the tar archive name, decompressing it according to its extension.
+func unzipTar(reader io.Reader, name string, basePath string, tracker *progressTracker) (*Manifest, error) {
+	if strings.HasSuffix(name, string(ArchiveTarXz)) {
+		xzReader, err := xz.NewReader(bufio.NewReader(reader))
+		if err != nil {
+			return nil, err
+		}
+		return tarUnzip(xzReader, basePath, tracker)
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer zipReadCloser.Close()
+	defer gzipReader.Close()
 
-	tarReadCloser := tar.NewReader(zipReadCloser)
+	return tarUnzip(gzipReader, basePath, tracker)
+}
 
-	header, err := tarReadCloser.Next()
-	unzippedFilePath := filepath.Join(basePath, header.Name)
+func tarUnzip(reader io.Reader, basePath string, tracker *progressTracker) (*Manifest, error) {
+	tarReadCloser := tar.NewReader(reader)
 
-	for true {
+	manifest := newManifestBuilder(basePath)
+	for {
+		header, err := tarReadCloser.Next()
 		if errors.Is(err, io.EOF) {
 			break
 		} else if err != nil {
-			return "", err
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			// PAX records for every following entry, e.g. the commit of git archive. Long names and other
+			// per entry records are already applied to header by the reader.
+			continue
+		}
+
+		path, err := prepareEntry(basePath, header.Name)
+		if err != nil {
+			return nil, err
 		}
-		path := filepath.Join(basePath, header.Name)
 
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(path, os.ModePerm); err != nil {
-				return "", err
+				return nil, err
 			}
+			manifest.addDir(header.Name, path, fs.FileMode(header.Mode), header.ModTime)
 		case tar.TypeSymlink:
-			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-				return "", err
+			if err := checkSymlink(basePath, path, header.Name, header.Linkname); err != nil {
+				return nil, err
 			}
-
 			if err := os.Symlink(header.Linkname, path); err != nil {
-				return "", err
+				return nil, err
 			}
-		case tar.TypeReg:
-			err := func() error {
-				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-					return err
-				}
-				outFile, err := os.Create(path)
-				if err != nil {
-					return err
-				}
-				defer outFile.Close()
-
-				if err = os.Chmod(path, fs.FileMode(header.Mode)); err != nil {
-					return err
-				}
-				if _, err := io.Copy(outFile, tarReadCloser); err != nil {
-					return err
-				}
-
-				return nil
-			}()
+			manifest.addFile(header.Name)
+		case tar.TypeLink:
+			targetPath, err := checkHardlink(basePath, header.Name, header.Linkname)
 			if err != nil {
-				return "", err
+				return nil, err
+			}
+			if err := os.Link(targetPath, path); err != nil {
+				return nil, err
+			}
+			manifest.addFile(header.Name)
+		case tar.TypeReg:
+			if err := writeEntry(path, fs.FileMode(header.Mode), header.ModTime, tarReadCloser); err != nil {
+				return nil, err
 			}
+			manifest.addFile(header.Name)
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			return nil, &UnsafeEntryError{header.Name, "device files and named pipes are not supported"}
 		default:
-			return "", errors.New(fmt.Sprintf("unsupported tar type: %v for name %s", header.Typeflag, header.Name))
+			return nil, errors.New(fmt.Sprintf("unsupported tar type: %v for name %s", header.Typeflag, header.Name))
 		}
 
-		header, err = tarReadCloser.Next()
+		tracker.add(0, 1)
 	}
 
-	return unzippedFilePath, nil
+	return manifest.finish()
 }
 
-func zipUnzip(zipFile fs.File, basePath string) (string, error) {
-	buff := bytes.NewBuffer([]byte{})
-	size, err := io.Copy(buff, zipFile)
+func zipUnzip(readerAt io.ReaderAt, size int64, basePath string, tracker *progressTracker) (*Manifest, error) {
+	zipReader, err := zip.NewReader(readerAt, size)
 	if err != nil {
-		return "", err
-	}
-	reader := bytes.NewReader(buff.Bytes())
-	zipReader, err := zip.NewReader(reader, size)
-	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	unzippedFilePath := filepath.Join(basePath, zipReader.File[0].Name)
-
+	manifest := newManifestBuilder(basePath)
 	for _, zipFile := range zipReader.File {
 		err := func() error {
-			fileReader, err := zipFile.Open()
+			path, err := prepareEntry(basePath, zipFile.Name)
 			if err != nil {
 				return err
 			}
-			defer fileReader.Close()
-			path := filepath.Join(basePath, zipFile.Name)
 
-			if zipFile.FileInfo().IsDir() {
-
-				if err := os.MkdirAll(path, zipFile.Mode()); err != nil {
+			mode := zipMode(zipFile)
+			if mode.IsDir() {
+				if err := os.MkdirAll(path, os.ModePerm); err != nil {
 					return err
 				}
-			} else {
+				manifest.addDir(zipFile.Name, path, mode, zipFile.Modified)
+				return nil
+			} else if mode&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe|fs.ModeSocket|fs.ModeIrregular) != 0 {
+				return &UnsafeEntryError{zipFile.Name, "device files and named pipes are not supported"}
+			}
 
-				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			fileReader, err := zipFile.Open()
+			if err != nil {
+				return err
+			}
+			defer fileReader.Close()
+
+			if mode&fs.ModeSymlink != 0 {
+				// The contents of a symlink entry are its target.
+				linkname, err := io.ReadAll(io.LimitReader(fileReader, 4096))
+				if err != nil {
 					return err
 				}
-				file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zipFile.Mode())
-				if err != nil {
+				if err := checkSymlink(basePath, path, zipFile.Name, string(linkname)); err != nil {
 					return err
 				}
-				defer file.Close()
-
-				if _, err = io.Copy(file, fileReader); err != nil {
+				if err := os.Symlink(string(linkname), path); err != nil {
 					return err
 				}
+			} else if err := writeEntry(path, mode, zipFile.Modified, fileReader); err != nil {
+				return err
 			}
+			manifest.addFile(zipFile.Name)
 			return nil
 		}()
 
 		if err != nil {
+			return nil, err
+		}
+		tracker.add(int64(zipFile.CompressedSize64), 1)
+	}
+	return manifest.finish()
+}
+
+// zipMode returns the mode of a zip entry. Entries that were not added on unix or macOS, e.g. by the zip
+// tools of windows, do not have permissions, so they get the default permissions.
+func zipMode(zipFile *zip.File) fs.FileMode {
+	const creatorUnix, creatorMacOSX = 3, 19
+	mode := zipFile.Mode()
+	if creator := zipFile.CreatorVersion >> 8; creator == creatorUnix || creator == creatorMacOSX {
+		return mode
+	}
+	if mode.IsDir() {
+		return fs.ModeDir | 0755
+	}
+	return mode&fs.ModeType | 0644
+}
+
+// writeEntry writes the contents of a regular file entry to path, restoring its permissions and modification time.
+func writeEntry(path string, mode fs.FileMode, modTime time.Time, contents io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, contents); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// The permissions passed to OpenFile are reduced by the umask.
+	if err := os.Chmod(path, mode.Perm()); err != nil {
+		return err
+	}
+	return restoreModTime(path, modTime)
+}
+
+func restoreModTime(path string, modTime time.Time) error {
+	if modTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// manifestBuilder collects the extracted entries of an archive, and the metadata of its directories, which is
+// only restored once every entry is extracted. Extracting an entry changes the modification time of its directory,
+// and a directory without write permission can not get any more entries.
+type manifestBuilder struct {
+	basePath string
+	names    []string
+	files    []string
+	dirs     []extractedDir
+}
+
+type extractedDir struct {
+	path    string
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func newManifestBuilder(basePath string) *manifestBuilder {
+	return &manifestBuilder{basePath: basePath, names: make([]string, 0), files: make([]string, 0), dirs: make([]extractedDir, 0)}
+}
+
+func (b *manifestBuilder) addFile(name string) {
+	name = path.Clean(filepath.ToSlash(name))
+	b.names = append(b.names, name)
+	b.files = append(b.files, name)
+}
+
+func (b *manifestBuilder) addDir(name string, dirPath string, mode fs.FileMode, modTime time.Time) {
+	b.names = append(b.names, path.Clean(filepath.ToSlash(name)))
+	b.dirs = append(b.dirs, extractedDir{dirPath, mode, modTime})
+}
+
+// finish restores the metadata of the directories and returns the manifest.
+func (b *manifestBuilder) finish() (*Manifest, error) {
+	for i := len(b.dirs) - 1; i >= 0; i-- {
+		dir := b.dirs[i]
+		if err := os.Chmod(dir.path, dir.mode.Perm()); err != nil {
+			return nil, err
+		}
+		if err := restoreModTime(dir.path, dir.modTime); err != nil {
+			return nil, err
+		}
+	}
+
+	topLevelDir := b.topLevelDir()
+	manifest := &Manifest{Root: filepath.Join(b.basePath, filepath.FromSlash(topLevelDir)), Files: make([]string, 0, len(b.files))}
+	for _, file := range b.files {
+		if len(topLevelDir) > 0 {
+			file = strings.TrimPrefix(file, topLevelDir+"/")
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	return manifest, nil
+}
+
+// topLevelDir returns the directory that contains every entry, or an empty string when there is none.
+func (b *manifestBuilder) topLevelDir() string {
+	topLevelDir, nested := "", false
+	for _, name := range b.names {
+		if name == "." {
+			continue
+		}
+		first, _, found := strings.Cut(name, "/")
+		if len(topLevelDir) == 0 {
+			topLevelDir = first
+		} else if first != topLevelDir {
+			return ""
+		}
+		nested = nested || found
+	}
+	if !nested {
+		return ""
+	}
+	return topLevelDir
+}
+
+// prepareEntry returns the path that the entry name is extracted to, after creating its parent directories.
+// A file or symlink that was extracted to the same path by an earlier entry is removed, so that the entry is
+// never written through a symlink.
+func prepareEntry(basePath string, name string) (string, error) {
+	path, err := entryPath(basePath, name)
+	if err != nil {
+		return "", err
+	}
+	if err := checkParents(basePath, path, name); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() {
+		if err := os.Remove(path); err != nil {
 			return "", err
 		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
 	}
-	return unzippedFilePath, nil
+
+	return path, nil
+}
+
+// entryPath returns the path that the entry name is extracted to, rejecting names that are absolute or that
+// have a .. element. Both / and \ are treated as separators, so that the same archive is rejected on every os.
+func entryPath(basePath string, name string) (string, error) {
+	if len(name) == 0 {
+		return "", &UnsafeEntryError{name, "the name is empty"}
+	}
+	if isAbsoluteName(name) {
+		return "", &UnsafeEntryError{name, "the path is absolute"}
+	}
+	if hasParentElement(name) {
+		return "", &UnsafeEntryError{name, "the path contains .."}
+	}
+	return filepath.Join(basePath, filepath.FromSlash(name)), nil
+}
+
+// checkParents rejects entries below a symlink that was extracted earlier. Every symlink is checked on its own,
+// but symlinks that point at each other could still resolve outside of basePath.
+func checkParents(basePath string, path string, name string) error {
+	relativePath, err := filepath.Rel(basePath, filepath.Dir(path))
+	if err != nil || relativePath == "." {
+		return err
+	}
+
+	parent := basePath
+	for _, element := range strings.Split(relativePath, string(filepath.Separator)) {
+		parent = filepath.Join(parent, element)
+		info, err := os.Lstat(parent)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return &UnsafeEntryError{name, "the path is below the symlink " + filepath.ToSlash(relativePath)}
+		}
+	}
+	return nil
+}
+
+// checkSymlink rejects symlinks at path that point outside of basePath. The target is resolved one element at a time,
+// and a .. element must follow a directory that was already extracted. After a symlink, e.g. s/.. with s -> .., the
+// .. would go up from the target of the symlink, and an entry that does not exist yet could still be extracted as one.
+func checkSymlink(basePath string, path string, name string, linkname string) error {
+	if len(linkname) == 0 {
+		return &UnsafeEntryError{name, "the symlink target is empty"}
+	}
+	if isAbsoluteName(linkname) {
+		return &UnsafeEntryError{name, "the symlink target " + linkname + " is absolute"}
+	}
+
+	target := filepath.Dir(path)
+	for _, element := range strings.FieldsFunc(linkname, func(r rune) bool { return r == '/' || r == '\\' }) {
+		switch element {
+		case ".":
+			continue
+		case "..":
+			if info, err := os.Lstat(target); err != nil || !info.IsDir() {
+				return &UnsafeEntryError{name, "the symlink target " + linkname + " goes up from an entry that is not a directory"}
+			}
+			target = filepath.Dir(target)
+		default:
+			target = filepath.Join(target, element)
+		}
+		if !isWithin(basePath, target) {
+			return &UnsafeEntryError{name, "the symlink target " + linkname + " is outside of the archive"}
+		}
+	}
+	return nil
+}
+
+// checkHardlink returns the path of the hardlink target linkname, which is the name of an earlier entry,
+// rejecting targets outside of basePath.
+func checkHardlink(basePath string, name string, linkname string) (string, error) {
+	targetPath, err := entryPath(basePath, linkname)
+	if err != nil {
+		return "", &UnsafeEntryError{name, "the hardlink target " + linkname + " is outside of the archive"}
+	}
+	if err := checkParents(basePath, targetPath, name); err != nil {
+		return "", err
+	}
+	if info, err := os.Lstat(targetPath); err != nil || !info.Mode().IsRegular() {
+		return "", &UnsafeEntryError{name, "the hardlink target " + linkname + " is not a file in the archive"}
+	}
+	return targetPath, nil
+}
+
+func isAbsoluteName(name string) bool {
+	return strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) || filepath.IsAbs(name) || len(filepath.VolumeName(name)) > 0 ||
+		(len(name) >= 2 && name[1] == ':')
+}
+
+func hasParentElement(name string) bool {
+	for _, element := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin returns whether path is basePath or one of its descendants, without resolving symlinks.
+func isWithin(basePath string, path string) bool {
+	relativePath, err := filepath.Rel(basePath, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
 }
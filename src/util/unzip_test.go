@@ -0,0 +1,351 @@
+package util
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/ulikunitz/xz"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testArchiveEntry struct {
+	name     string
+	typeflag byte
+	mode     fs.FileMode
+	linkname string
+	contents string
+	modTime  time.Time
+	format   tar.Format
+}
+
+func testFile(name string, contents string) testArchiveEntry {
+	return testArchiveEntry{name: name, typeflag: tar.TypeReg, mode: 0644, contents: contents}
+}
+
+func testDir(name string) testArchiveEntry {
+	return testArchiveEntry{name: name, typeflag: tar.TypeDir, mode: fs.ModeDir | 0755}
+}
+
+func testSymlink(name string, linkname string) testArchiveEntry {
+	return testArchiveEntry{name: name, typeflag: tar.TypeSymlink, mode: fs.ModeSymlink | 0777, linkname: linkname}
+}
+
+func testHardlink(name string, linkname string) testArchiveEntry {
+	return testArchiveEntry{name: name, typeflag: tar.TypeLink, mode: 0644, linkname: linkname}
+}
+
+func newTestTarGz(t testing.TB, entries ...testArchiveEntry) []byte {
+	buf := new(bytes.Buffer)
+	gzipWriter := gzip.NewWriter(buf)
+	writeTestTar(t, gzipWriter, entries...)
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newTestTarXz(t testing.TB, entries ...testArchiveEntry) []byte {
+	buf := new(bytes.Buffer)
+	xzWriter, err := xz.NewWriter(buf)
+	if err != nil {
+		t.Fatalf("Failed to create xz writer: %v", err)
+	}
+	writeTestTar(t, xzWriter, entries...)
+	if err := xzWriter.Close(); err != nil {
+		t.Fatalf("Failed to close xz: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeTestTar(t testing.TB, writer io.Writer, entries ...testArchiveEntry) {
+	tarWriter := tar.NewWriter(writer)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:     entry.name,
+			Typeflag: entry.typeflag,
+			Mode:     int64(entry.mode.Perm()),
+			Linkname: entry.linkname,
+			Size:     int64(len(entry.contents)),
+			ModTime:  entry.modTime,
+			Format:   entry.format,
+		}
+		if entry.typeflag == tar.TypeXGlobalHeader {
+			header.PAXRecords = map[string]string{"comment": entry.contents}
+			header.Size = 0
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if header.Size == 0 {
+			continue
+		}
+		if _, err := tarWriter.Write([]byte(entry.contents)); err != nil {
+			t.Fatalf("Failed to write tar contents: %v", err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+}
+
+func newTestZip(t testing.TB, entries ...testArchiveEntry) []byte {
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: entry.modTime}
+		header.SetMode(entry.mode)
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("Failed to write zip header: %v", err)
+		}
+		contents := entry.contents
+		if entry.mode&fs.ModeSymlink != 0 {
+			contents = entry.linkname
+		}
+		if _, err := writer.Write([]byte(contents)); err != nil {
+			t.Fatalf("Failed to write zip contents: %v", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// unzipTestArchive extracts archive into a root directory next to nothing else, returning the root directory.
+func unzipTestArchive(t testing.TB, name string, archive []byte) (string, *Manifest, error) {
+	archivePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(archivePath, archive, 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	rootDir := filepath.Join(t.TempDir(), "root")
+	if err := os.Mkdir(rootDir, 0755); err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	manifest, err := Unzip(file, rootDir, nil)
+	return rootDir, manifest, err
+}
+
+// assertNothingEscaped fails when anything was extracted next to rootDir, or when rootDir has a symlink to
+// somewhere outside of it.
+func assertNothingEscaped(t testing.TB, rootDir string) {
+	siblings, err := os.ReadDir(filepath.Dir(rootDir))
+	if err != nil {
+		t.Fatalf("Failed to read parent of root: %v", err)
+	}
+	if len(siblings) != 1 {
+		t.Fatalf(`Unzip() wrote %d entries next to the root, Wanted none`, len(siblings)-1)
+	}
+
+	err = filepath.WalkDir(rootDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		linkname, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if filepath.IsAbs(linkname) || !isWithin(rootDir, filepath.Join(filepath.Dir(path), linkname)) {
+			t.Fatalf(`Unzip() created symlink %s to %s, Wanted a target inside the root`, path, linkname)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk root: %v", err)
+	}
+}
+
+func TestUnzipTarGz(t *testing.T) {
+	archive := newTestTarGz(t,
+		testDir("node-v18.2.0-linux-x64/"),
+		testFile("node-v18.2.0-linux-x64/lib/node_modules/npm/bin/npm-cli.js", "npm"),
+		testSymlink("node-v18.2.0-linux-x64/bin/npm", "../lib/node_modules/npm/bin/npm-cli.js"),
+		testHardlink("node-v18.2.0-linux-x64/lib/npm-cli.js", "node-v18.2.0-linux-x64/lib/node_modules/npm/bin/npm-cli.js"),
+		testFile("node-v18.2.0-linux-x64/bin/npm", "replaced symlink"),
+	)
+
+	rootDir, manifest, err := unzipTestArchive(t, "node.tar.gz", archive)
+	if err != nil {
+		t.Fatalf(`Unzip() error = %v`, err)
+	}
+	path := manifest.Root
+	if path != filepath.Join(rootDir, "node-v18.2.0-linux-x64") {
+		t.Fatalf(`Unzip() root = %q, Wanted = %q`, path, filepath.Join(rootDir, "node-v18.2.0-linux-x64"))
+	}
+	for name, wanted := range map[string]string{"lib/npm-cli.js": "npm", "bin/npm": "replaced symlink"} {
+		contents, err := os.ReadFile(filepath.Join(path, name))
+		if err != nil || string(contents) != wanted {
+			t.Fatalf(`Unzip() %s = %q, %v, Wanted = %q`, name, contents, err, wanted)
+		}
+	}
+	assertNothingEscaped(t, rootDir)
+}
+
+func TestUnzipRestoresMetadata(t *testing.T) {
+	modTime := time.Date(2022, 5, 17, 12, 0, 0, 0, time.UTC)
+	longName := "node/" + strings.Repeat("long/", 30) + "name.js"
+	gnuLongName := "node/" + strings.Repeat("gnu/", 40) + "name.js"
+	archive := newTestTarGz(t,
+		testArchiveEntry{name: "pax_global_header", typeflag: tar.TypeXGlobalHeader, contents: "commit"},
+		testArchiveEntry{name: "node/", typeflag: tar.TypeDir, mode: fs.ModeDir | 0750, modTime: modTime},
+		testArchiveEntry{name: "node/bin/", typeflag: tar.TypeDir, mode: fs.ModeDir | 0555, modTime: modTime},
+		testArchiveEntry{name: "node/bin/node", typeflag: tar.TypeReg, mode: 0755, contents: "node", modTime: modTime},
+		testArchiveEntry{name: longName, typeflag: tar.TypeReg, mode: 0644, contents: "pax", format: tar.FormatPAX},
+		testArchiveEntry{name: gnuLongName, typeflag: tar.TypeReg, mode: 0644, contents: "gnu", format: tar.FormatGNU},
+		testHardlink("node/bin/nodejs", "node/bin/node"),
+	)
+
+	_, manifest, err := unzipTestArchive(t, "node.tar.gz", archive)
+	if err != nil {
+		t.Fatalf(`Unzip() error = %v`, err)
+	}
+	defer os.Chmod(filepath.Join(manifest.Root, "bin"), 0755)
+
+	expectFiles := []string{"bin/node", strings.TrimPrefix(longName, "node/"), strings.TrimPrefix(gnuLongName, "node/"), "bin/nodejs"}
+	if filepath.Base(manifest.Root) != "node" || !slices.Equal(manifest.Files, expectFiles) {
+		t.Fatalf(`Unzip() = %v, Wanted root node and files %q`, manifest, expectFiles)
+	}
+	for path, expectMode := range map[string]fs.FileMode{"": fs.ModeDir | 0750, "bin": fs.ModeDir | 0555, "bin/node": 0755} {
+		info, err := os.Stat(filepath.Join(manifest.Root, path))
+		if err != nil || info.Mode() != expectMode || !info.ModTime().Equal(modTime) {
+			t.Fatalf(`Unzip() %q = %v, %v, Wanted mode %v and modification time %v`, path, info.Mode(), info.ModTime(), expectMode, modTime)
+		}
+	}
+	nodeInfo, _ := os.Stat(filepath.Join(manifest.Root, "bin", "node"))
+	if nodejsInfo, err := os.Stat(filepath.Join(manifest.Root, "bin", "nodejs")); err != nil || !os.SameFile(nodeInfo, nodejsInfo) {
+		t.Fatalf(`Unzip() bin/nodejs is not a hardlink of bin/node, error = %v`, err)
+	}
+}
+
+func TestUnzipManifestWithoutTopLevelDir(t *testing.T) {
+	archive := newTestTarGz(t, testFile("bin/node", "node"), testFile("README.md", "readme"))
+
+	rootDir, manifest, err := unzipTestArchive(t, "node.tar.gz", archive)
+	if err != nil {
+		t.Fatalf(`Unzip() error = %v`, err)
+	}
+	if manifest.Root != rootDir || !slices.Equal(manifest.Files, []string{"bin/node", "README.md"}) {
+		t.Fatalf(`Unzip() = %v, Wanted root %q and files %q`, manifest, rootDir, []string{"bin/node", "README.md"})
+	}
+}
+
+func TestUnzipTarXz(t *testing.T) {
+	archive := newTestTarXz(t,
+		testDir("node-v18.2.0-linux-x64/"),
+		testFile("node-v18.2.0-linux-x64/bin/node", "node"),
+	)
+
+	rootDir, manifest, err := unzipTestArchive(t, "node.tar.xz", archive)
+	if err != nil {
+		t.Fatalf(`Unzip() error = %v`, err)
+	}
+	contents, err := os.ReadFile(filepath.Join(manifest.Root, "bin", "node"))
+	if err != nil || string(contents) != "node" {
+		t.Fatalf(`Unzip() bin/node = %q, %v, Wanted = %q`, contents, err, "node")
+	}
+	assertNothingEscaped(t, rootDir)
+}
+
+func TestUnzipZip(t *testing.T) {
+	modTime := time.Date(2022, 5, 17, 12, 0, 0, 0, time.UTC)
+	archive := newTestZip(t,
+		testDir("node-v18.2.0-win-x64/"),
+		testArchiveEntry{name: "node-v18.2.0-win-x64/node.exe", mode: 0755, contents: "node", modTime: modTime},
+		testSymlink("node-v18.2.0-win-x64/node", "node.exe"),
+	)
+
+	rootDir, manifest, err := unzipTestArchive(t, "node.zip", archive)
+	if err != nil {
+		t.Fatalf(`Unzip() error = %v`, err)
+	}
+	contents, err := os.ReadFile(filepath.Join(manifest.Root, "node"))
+	if err != nil || string(contents) != "node" {
+		t.Fatalf(`Unzip() node = %q, %v, Wanted = %q`, contents, err, "node")
+	}
+	info, err := os.Stat(filepath.Join(manifest.Root, "node.exe"))
+	if err != nil || info.Mode() != 0755 || !info.ModTime().Equal(modTime) {
+		t.Fatalf(`Unzip() node.exe = %v, %v, Wanted mode %v and modification time %v`, info.Mode(), info.ModTime(), fs.FileMode(0755), modTime)
+	}
+	assertNothingEscaped(t, rootDir)
+}
+
+// unsafeTestArchives is the corpus of crafted archives that must be rejected, it also seeds FuzzUnzip.
+var unsafeTestArchives = map[string][]testArchiveEntry{
+	"parent path":               {testFile("../evil", "evil")},
+	"nested parent path":        {testDir("node/"), testFile("node/../../evil", "evil")},
+	"backslash parent path":     {testFile(`node\..\..\evil`, "evil")},
+	"absolute path":             {testFile("/tmp/evil", "evil")},
+	"windows absolute path":     {testFile("C:/evil", "evil")},
+	"empty name":                {testFile("", "evil")},
+	"symlink to parent":         {testSymlink("node/evil", "../../evil")},
+	"symlink to absolute path":  {testSymlink("node/evil", "/etc/passwd")},
+	"file below symlink":        {testSymlink("node/lib", "."), testFile("node/lib/evil", "evil")},
+	"symlink below symlink":     {testSymlink("node/lib", "."), testSymlink("node/lib/evil", "../evil")},
+	"symlink through symlink":   {testDir("node/"), testSymlink("node/s", ".."), testSymlink("node/evil", "s/../..")},
+	"symlink to later symlink":  {testDir("node/"), testSymlink("node/evil", "s/../.."), testSymlink("node/s", "..")},
+	"hardlink to parent":        {testHardlink("node/evil", "../evil")},
+	"hardlink to absolute path": {testHardlink("node/evil", "/etc/passwd")},
+	"hardlink to symlink dir":   {testSymlink("node/lib", "."), testHardlink("node/evil", "node/lib/file")},
+	"character device":          {{name: "node/tty", typeflag: tar.TypeChar, mode: fs.ModeDevice | fs.ModeCharDevice | 0644}},
+	"block device":              {{name: "node/sda", typeflag: tar.TypeBlock, mode: fs.ModeDevice | 0644}},
+	"named pipe":                {{name: "node/fifo", typeflag: tar.TypeFifo, mode: fs.ModeNamedPipe | 0644}},
+}
+
+func TestUnzipRejectsUnsafeTarGz(t *testing.T) {
+	for name, entries := range unsafeTestArchives {
+		t.Run(name, func(t *testing.T) {
+			rootDir, _, err := unzipTestArchive(t, "node.tar.gz", newTestTarGz(t, entries...))
+			if !errors.Is(err, ErrUnsafeArchive) {
+				t.Fatalf(`Unzip() error = %v, Wanted = %v`, err, ErrUnsafeArchive)
+			}
+			assertNothingEscaped(t, rootDir)
+		})
+	}
+}
+
+func TestUnzipRejectsUnsafeZip(t *testing.T) {
+	for name, entries := range unsafeTestArchives {
+		if entries[len(entries)-1].typeflag == tar.TypeLink || entries[len(entries)-1].typeflag == tar.TypeBlock {
+			// Zip archives do not have hardlinks, and block devices are stored as character devices.
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			rootDir, _, err := unzipTestArchive(t, "node.zip", newTestZip(t, entries...))
+			if !errors.Is(err, ErrUnsafeArchive) {
+				t.Fatalf(`Unzip() error = %v, Wanted = %v`, err, ErrUnsafeArchive)
+			}
+			assertNothingEscaped(t, rootDir)
+		})
+	}
+}
+
+func FuzzUnzip(f *testing.F) {
+	for _, entries := range unsafeTestArchives {
+		f.Add(newTestTarGz(f, entries...), false)
+		f.Add(newTestZip(f, entries...), true)
+	}
+
+	f.Fuzz(func(t *testing.T, archive []byte, isZip bool) {
+		name := "node.tar.gz"
+		if isZip {
+			name = "node.zip"
+		}
+		rootDir, _, _ := unzipTestArchive(t, name, archive)
+		assertNothingEscaped(t, rootDir)
+	})
+}
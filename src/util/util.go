@@ -2,9 +2,11 @@ package util
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -12,25 +14,31 @@ import (
 var VERSION = "UNSET"
 
 type InstallationInfo struct {
-	FileExtension            string
-	FileNameWithExtension    string
+	Platform                 string
+	Arch                     string
+	Libc                     string
 	FileNameWithoutExtension string
 }
 
-func GetInstallationInfo(version string) (*InstallationInfo, error) {
+// FileName returns the name of the archive of the version in format.
+func (i *InstallationInfo) FileName(format ArchiveFormat) string {
+	return i.FileNameWithoutExtension + string(format)
+}
+
+// Target returns the target of the archive.
+func (i *InstallationInfo) Target() Target {
+	return Target{i.Platform, i.Arch, i.Libc}
+}
+
+func GetInstallationInfo(version string, target Target) (*InstallationInfo, error) {
 	version, err := NormalizeVersion(version)
 	if err != nil {
 		return nil, err
 	}
 
-	normalizedArch := getNodeArch()
-	normalizedPlatform := getNodeOs()
-
-	fileExtension := getFileExtension()
-	fileNameWithoutExtension := "node-" + version + "-" + normalizedPlatform + "-" + normalizedArch
-	fileNameWithExtension := fileNameWithoutExtension + fileExtension
+	fileNameWithoutExtension := "node-" + version + "-" + target.String()
 
-	return &InstallationInfo{fileExtension, fileNameWithExtension, fileNameWithoutExtension}, nil
+	return &InstallationInfo{target.Platform, target.Arch, target.Libc, fileNameWithoutExtension}, nil
 }
 
 func GetNvmcHomePath() (string, error) {
@@ -69,6 +77,39 @@ func GetVersionPath(version string) (string, error) {
 	return versionDir, nil
 }
 
+// GetBinPath returns the directory of an installed version that contains the node executable.
+func GetBinPath(version string) (string, error) {
+	versionDir, err := GetVersionPath(version)
+	if err != nil {
+		return "", err
+	}
+	metadata, err := ReadInstallMetadata(version)
+	if err != nil {
+		return "", err
+	}
+
+	if metadata.Platform == "win" {
+		return filepath.Join(versionDir, metadata.Root), nil
+	}
+	return filepath.Join(versionDir, metadata.Root, "bin"), nil
+}
+
+// GetNodePath returns the node executable of an installed version.
+func GetNodePath(version string) (string, error) {
+	binPath, err := GetBinPath(version)
+	if err != nil {
+		return "", err
+	}
+	metadata, err := ReadInstallMetadata(version)
+	if err != nil {
+		return "", err
+	}
+	if metadata.Platform == "win" {
+		return filepath.Join(binPath, "node.exe"), nil
+	}
+	return filepath.Join(binPath, "node"), nil
+}
+
 func GetSymLinkPath() (string, error) {
 	nvmcHome, err := GetNvmcHomePath()
 	if err != nil {
@@ -78,6 +119,75 @@ func GetSymLinkPath() (string, error) {
 	return symLink, nil
 }
 
+// ReplaceSymlink points the symlink at path to target. The new symlink is created next to path and renamed over it,
+// so that path always exists. Windows can not rename over a directory symlink, there the old symlink is removed first.
+func ReplaceSymlink(target string, path string) error {
+	tempPath := path + ".tmp-" + strconv.Itoa(os.Getpid())
+	if err := os.Remove(tempPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Symlink(target, tempPath); err != nil {
+		return err
+	}
+
+	err := os.Rename(tempPath, path)
+	if err != nil && runtime.GOOS == "windows" {
+		if removeErr := os.Remove(path); removeErr == nil || errors.Is(removeErr, os.ErrNotExist) {
+			err = os.Rename(tempPath, path)
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+	}
+	return err
+}
+
+// GetStagingPath returns the directory that versions are extracted to before they are moved into the versions
+// directory. It is inside NVMC_HOME, so that moving a version is a rename on the same file system.
+func GetStagingPath(version string) (string, error) {
+	nvmcHome, err := GetNvmcHomePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nvmcHome, "staging", version), nil
+}
+
+// GetPreviousVersionPath returns the directory that an installed version is moved to while it is replaced, e.g. by
+// nvmc repair. It is next to the version, outside of the staging directory that the next install removes.
+func GetPreviousVersionPath(version string) (string, error) {
+	versionsDir, err := GetVersionsPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(versionsDir, "."+version+".previous"), nil
+}
+
+// RestorePreviousVersion moves version back from GetPreviousVersionPath when it was not replaced because nvmc was
+// killed in between, returning whether it was restored. When the replacement is in place, the previous installation
+// is removed instead. The caller must hold the lock of version.
+func RestorePreviousVersion(version string) (bool, error) {
+	previousDir, err := GetPreviousVersionPath(version)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(previousDir); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	versionDir, err := GetVersionPath(version)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(versionDir); err == nil {
+		return false, os.RemoveAll(previousDir)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	return true, os.Rename(previousDir, versionDir)
+}
+
 func NormalizeVersion(version string) (string, error) {
 	if len(version) == 0 {
 		return "", errors.New("version is required")
@@ -90,14 +200,6 @@ func NormalizeVersion(version string) (string, error) {
 	return version, nil
 }
 
-func getFileExtension() string {
-	if runtime.GOOS == "windows" {
-		return ".zip"
-	}
-
-	return ".tar.gz"
-}
-
 func getNodeOs() string {
 	switch runtime.GOOS {
 	case "windows":
@@ -117,3 +219,25 @@ func getNodeArch() string {
 		return runtime.GOARCH
 	}
 }
+
+// WriteFileAtomic writes contents to path through a temporary file in the same directory, so that readers see
+// either the old or the new contents.
+func WriteFileAtomic(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(contents); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), path)
+}
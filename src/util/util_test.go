@@ -1,6 +1,8 @@
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -31,3 +33,48 @@ func TestNormalizeVersionErrorOnEmptyVersion(t *testing.T) {
 		t.Fatalf(`NormalizeVersion(%q) = %q, %v, Wanted = %q, %v`, initVersion, version, err, expectVersion, expectError)
 	}
 }
+
+func TestReplaceSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "nodejs")
+	for _, target := range []string{filepath.Join(dir, "v18.2.0"), filepath.Join(dir, "v20.11.0")} {
+		if err := os.Mkdir(target, 0755); err != nil {
+			t.Fatalf("Failed to create target: %v", err)
+		}
+		if err := ReplaceSymlink(target, link); err != nil {
+			t.Fatalf(`ReplaceSymlink(%q) error = %v`, target, err)
+		}
+		if actual, err := os.Readlink(link); err != nil || actual != target {
+			t.Fatalf(`ReplaceSymlink(%q) target = %q, %v, Wanted = %q`, target, actual, err, target)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf(`ReplaceSymlink() left %d entries, %v, Wanted = 3`, len(entries), err)
+	}
+}
+
+func TestRestorePreviousVersion(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	versionDir, _ := GetVersionPath("v18.2.0")
+	previousDir, _ := GetPreviousVersionPath("v18.2.0")
+	writeTestFile(t, filepath.Join(previousDir, "node"), "previous")
+
+	// The replacement was not moved into place, the previous installation is restored.
+	if restored, err := RestorePreviousVersion("v18.2.0"); err != nil || !restored {
+		t.Fatalf(`RestorePreviousVersion() = %v, %v, Wanted = true`, restored, err)
+	}
+	if contents, err := os.ReadFile(filepath.Join(versionDir, "node")); err != nil || string(contents) != "previous" {
+		t.Fatalf(`RestorePreviousVersion() node = %q, %v, Wanted = %q`, contents, err, "previous")
+	}
+
+	// The replacement was moved into place, the previous installation is removed.
+	writeTestFile(t, filepath.Join(previousDir, "node"), "previous")
+	if restored, err := RestorePreviousVersion("v18.2.0"); err != nil || restored {
+		t.Fatalf(`RestorePreviousVersion() = %v, %v, Wanted = false`, restored, err)
+	}
+	if _, err := os.Stat(previousDir); !os.IsNotExist(err) {
+		t.Fatalf(`RestorePreviousVersion() left %s, error = %v`, previousDir, err)
+	}
+}
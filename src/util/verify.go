@@ -0,0 +1,119 @@
+package util
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrNoManifest is returned when an installed version can not be verified, because the files it was installed with
+// were not recorded.
+var ErrNoManifest = errors.New("no manifest was recorded")
+
+// ErrLinkedVersion is returned when an installed version can not be verified, because it is an external build
+// registered with nvmc link.
+var ErrLinkedVersion = errors.New("linked version")
+
+// DamagedFile is a file of an installed version that differs from when it was installed.
+type DamagedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ListFiles returns the files and symlinks in root, relative to root and separated by /, like the files of a Manifest.
+func ListFiles(root string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry, err error) error {
+		if err != nil || dirEntry.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	return files, err
+}
+
+// HashFiles returns the hash of each of files, relative to root. The hash of a file is its sha256 checksum, prefixed
+// with the algorithm, e.g. sha256:<hex>. The hash of a symlink is its target, prefixed with symlink:.
+func HashFiles(root string, files []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(files))
+	for _, file := range files {
+		hash, err := hashFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		hashes[file] = hash
+	}
+	return hashes, nil
+}
+
+func hashFile(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return "symlink:" + filepath.ToSlash(target), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := ChecksumSha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return string(ChecksumSha256) + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyInstall returns the files of the installed version that are missing, or that changed since it was installed.
+// Files added since, e.g. global npm packages, are not checked. Fails with ErrNoManifest for a version installed
+// by an older nvmc, or ErrLinkedVersion for a linked version. A version installed before nvmc recorded the hashes is
+// only checked for missing files.
+func VerifyInstall(version string) ([]DamagedFile, error) {
+	metadata, err := ReadInstallMetadata(version)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.IsLinked() {
+		return nil, ErrLinkedVersion
+	}
+	if metadata.IsLegacy() || len(metadata.Files) == 0 {
+		return nil, ErrNoManifest
+	}
+	versionDir, err := GetVersionPath(version)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Join(versionDir, metadata.Root)
+
+	damaged := make([]DamagedFile, 0)
+	for _, file := range metadata.Files {
+		hash, err := hashFile(filepath.Join(root, filepath.FromSlash(file)))
+		if errors.Is(err, os.ErrNotExist) {
+			damaged = append(damaged, DamagedFile{file, "missing"})
+		} else if err != nil {
+			damaged = append(damaged, DamagedFile{file, "unreadable, " + err.Error()})
+		} else if expected, ok := metadata.FileHashes[file]; ok && hash != expected {
+			damaged = append(damaged, DamagedFile{file, "modified"})
+		}
+	}
+	sort.Slice(damaged, func(i, j int) bool {
+		return damaged[i].Path < damaged[j].Path
+	})
+	return damaged, nil
+}
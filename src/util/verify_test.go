@@ -0,0 +1,103 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestVerifiedInstall(t *testing.T, version string, files map[string]string) string {
+	t.Helper()
+	versionDir, _ := GetVersionPath(version)
+	root := filepath.Join(versionDir, "node-"+version)
+	names := make([]string, 0)
+	for name, contents := range files {
+		writeTestFile(t, filepath.Join(root, filepath.FromSlash(name)), contents)
+		names = append(names, name)
+	}
+	if err := os.Symlink("node", filepath.Join(root, "bin", "nodejs")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	names = append(names, "bin/nodejs")
+
+	hashes, err := HashFiles(root, names)
+	if err != nil {
+		t.Fatalf(`HashFiles() error = %v`, err)
+	}
+	metadata := &InstallMetadata{Version: version, Root: "node-" + version, InstalledAt: time.Now(), Files: names, FileHashes: hashes}
+	if err := WriteInstallMetadata(versionDir, metadata); err != nil {
+		t.Fatalf(`WriteInstallMetadata() error = %v`, err)
+	}
+	return root
+}
+
+func TestHashFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "bin", "node"), "")
+	if err := os.Symlink("node", filepath.Join(root, "bin", "nodejs")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	hashes, err := HashFiles(root, []string{"bin/node", "bin/nodejs"})
+	wanted := map[string]string{"bin/node": "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "bin/nodejs": "symlink:node"}
+	if err != nil || !reflect.DeepEqual(hashes, wanted) {
+		t.Fatalf(`HashFiles() = %v, %v, Wanted = %v`, hashes, err, wanted)
+	}
+}
+
+func TestVerifyInstall(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	root := writeTestVerifiedInstall(t, "v18.2.0", map[string]string{"bin/node": "node", "bin/npm": "npm", "README.md": "readme"})
+
+	damaged, err := VerifyInstall("v18.2.0")
+	if err != nil || len(damaged) != 0 {
+		t.Fatalf(`VerifyInstall() = %v, %v, Wanted no damaged files`, damaged, err)
+	}
+
+	if err := os.Remove(filepath.Join(root, "bin", "node")); err != nil {
+		t.Fatalf("Failed to remove node: %v", err)
+	}
+	writeTestFile(t, filepath.Join(root, "bin", "npm"), "changed")
+	if err := os.Remove(filepath.Join(root, "bin", "nodejs")); err != nil {
+		t.Fatalf("Failed to remove symlink: %v", err)
+	}
+	if err := os.Symlink("npm", filepath.Join(root, "bin", "nodejs")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	writeTestFile(t, filepath.Join(root, "lib", "node_modules", "typescript", "package.json"), "{}")
+
+	damaged, err = VerifyInstall("v18.2.0")
+	wanted := []DamagedFile{{"bin/node", "missing"}, {"bin/nodejs", "modified"}, {"bin/npm", "modified"}}
+	if err != nil || !reflect.DeepEqual(damaged, wanted) {
+		t.Fatalf(`VerifyInstall() = %v, %v, Wanted = %v`, damaged, err, wanted)
+	}
+}
+
+func TestVerifyInstallOfLegacyVersion(t *testing.T) {
+	t.Setenv("NVMC_HOME", t.TempDir())
+	versionDir, _ := GetVersionPath("v18.2.0")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+
+	if damaged, err := VerifyInstall("v18.2.0"); err != ErrNoManifest {
+		t.Fatalf(`VerifyInstall() = %v, %v, Wanted = %v`, damaged, err, ErrNoManifest)
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "bin", "node"), "node")
+	writeTestFile(t, filepath.Join(root, "include", "node", "node.h"), "")
+	if err := os.Symlink("node", filepath.Join(root, "bin", "nodejs")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	files, err := ListFiles(root)
+	wanted := []string{"bin/node", "bin/nodejs", "include/node/node.h"}
+	if err != nil || !reflect.DeepEqual(files, wanted) {
+		t.Fatalf(`ListFiles() = %v, %v, Wanted = %v`, files, err, wanted)
+	}
+}
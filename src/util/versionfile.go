@@ -0,0 +1,106 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrVersionFileNotFound = errors.New("no .nvmrc, .node-version or package.json with a node version was found")
+
+// VersionFile is a project file that pins the node version.
+type VersionFile struct {
+	// Path of the file that supplied the version.
+	Path string
+	// Field within the file that supplied the version, only set for package.json.
+	Field string
+	// Expression is the version expression read from the file, it may need to be resolved.
+	Expression string
+}
+
+// FindVersionFile walks up from dir until it finds a .nvmrc, .node-version, or package.json with
+// volta.node or engines.node set. Within a single directory the files are checked in that order.
+func FindVersionFile(dir string) (*VersionFile, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for _, fileName := range []string{".nvmrc", ".node-version"} {
+			path := filepath.Join(dir, fileName)
+			expression, err := readVersionFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			} else if err == nil && len(expression) > 0 {
+				return &VersionFile{Path: path, Expression: expression}, nil
+			}
+		}
+
+		path := filepath.Join(dir, "package.json")
+		field, expression, err := readPackageJsonVersion(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		} else if err == nil && len(expression) > 0 {
+			return &VersionFile{Path: path, Field: field, Expression: expression}, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrVersionFileNotFound
+		}
+		dir = parent
+	}
+}
+
+// readVersionFile returns the first line of a .nvmrc or .node-version file that is not blank or a comment.
+func readVersionFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+// readPackageJsonVersion returns volta.node, or engines.node when volta.node is not set, from a package.json file.
+func readPackageJsonVersion(path string) (string, string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	packageJson := struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+		Volta struct {
+			Node string `json:"node"`
+		} `json:"volta"`
+	}{}
+	if err := json.Unmarshal(contents, &packageJson); err != nil {
+		return "", "", errors.New("unable to parse " + path + ": " + err.Error())
+	}
+
+	if len(strings.TrimSpace(packageJson.Volta.Node)) > 0 {
+		return "volta.node", strings.TrimSpace(packageJson.Volta.Node), nil
+	}
+	if len(strings.TrimSpace(packageJson.Engines.Node)) > 0 {
+		return "engines.node", strings.TrimSpace(packageJson.Engines.Node), nil
+	}
+
+	return "", "", nil
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatalf("Failed to make directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestFindVersionFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		field      string
+		expression string
+		fileName   string
+	}{
+		{"nvmrc", map[string]string{".nvmrc": "# pinned\n  lts/hydrogen \n"}, "", "lts/hydrogen", ".nvmrc"},
+		{"node-version", map[string]string{".node-version": "18.2.0\n"}, "", "18.2.0", ".node-version"},
+		{"nvmrc before node-version", map[string]string{".nvmrc": "20", ".node-version": "18"}, "", "20", ".nvmrc"},
+		{"engines", map[string]string{"package.json": `{"engines":{"node":">=18"}}`}, "engines.node", ">=18", "package.json"},
+		{"volta before engines", map[string]string{"package.json": `{"engines":{"node":">=18"},"volta":{"node":"18.2.0"}}`}, "volta.node", "18.2.0", "package.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, contents := range tt.files {
+				writeTestFile(t, filepath.Join(dir, name), contents)
+			}
+			nested := filepath.Join(dir, "a", "b")
+			if err := os.MkdirAll(nested, os.ModePerm); err != nil {
+				t.Fatalf("Failed to make nested directory: %v", err)
+			}
+
+			versionFile, err := FindVersionFile(nested)
+			if err != nil {
+				t.Fatalf(`FindVersionFile() error = %v`, err)
+			}
+			if versionFile.Expression != tt.expression || versionFile.Field != tt.field || versionFile.Path != filepath.Join(dir, tt.fileName) {
+				t.Fatalf(`FindVersionFile() = %+v, Wanted = %q, %q, %q`, *versionFile, tt.expression, tt.field, tt.fileName)
+			}
+		})
+	}
+}
+
+func TestFindVersionFileSkipsPackageJsonWithoutVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ".nvmrc"), "18")
+	writeTestFile(t, filepath.Join(dir, "project", "package.json"), `{"name":"project"}`)
+
+	versionFile, err := FindVersionFile(filepath.Join(dir, "project"))
+	if err != nil || versionFile.Path != filepath.Join(dir, ".nvmrc") {
+		t.Fatalf(`FindVersionFile() = %+v, %v, Wanted = %q`, versionFile, err, filepath.Join(dir, ".nvmrc"))
+	}
+}
+
+func TestFindVersionFileNotFound(t *testing.T) {
+	// The temp dir's parents could contain a version file, only check the error type when nothing is found.
+	_, err := FindVersionFile(t.TempDir())
+	if err != nil && !errors.Is(err, ErrVersionFileNotFound) {
+		t.Fatalf(`FindVersionFile() error = %v, Wanted = %v`, err, ErrVersionFileNotFound)
+	}
+}